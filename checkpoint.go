@@ -0,0 +1,124 @@
+package main
+
+import (
+	json2 "encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/gogen/pkg/gen"
+)
+
+const (
+	checkpointVersion = 1
+)
+
+// Checkpoint is a saved state of generation, which can be resumed with the same config
+type Checkpoint struct {
+	Version    int
+	ConfigHash string
+	Seed       uint64
+	ShardIndex int
+	ShardCount int
+	// Now is a reference time of the first run, it is kept to generate the same dates
+	Now       time.Time
+	Iteration int64
+	Elapsed   time.Duration
+	Entities  []EntityCheckpoint
+}
+
+type EntityCheckpoint struct {
+	Filepath string
+	Records  int64
+	Written  int64
+	Bytes    int64
+	// Offset is a size of the output, records written after the checkpoint are truncated on resume
+	Offset int64
+	// Parts of the split output, Offset is a size of the last one
+	Parts   []OutputPart     `json:",omitempty"`
+	Elapsed time.Duration    `json:",omitempty"`
+	Fields  []gen.FieldStats `json:",omitempty"`
+}
+
+func readCheckpoint(path string) (*Checkpoint, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "read checkpoint")
+	}
+	checkpoint := new(Checkpoint)
+	err = json2.Unmarshal(b, checkpoint)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "unmarshal checkpoint %s", path)
+	}
+	if checkpoint.Version != checkpointVersion {
+		return nil, errors.Errorf("unsupported checkpoint version %d", checkpoint.Version)
+	}
+	return checkpoint, nil
+}
+
+// writeCheckpoint replaces checkpoint atomically, so it is never left partially written
+func writeCheckpoint(path string, checkpoint *Checkpoint) error {
+	checkpoint.Version = checkpointVersion
+	b, err := json2.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return errors.WithMessage(err, "marshal checkpoint")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.WithMessage(err, "create temp checkpoint")
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(b)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.WithMessagef(err, "write %s", tmp.Name())
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return errors.WithMessage(err, "replace checkpoint")
+	}
+	return nil
+}
+
+// resumeState validates that the checkpoint belongs to the config and returns the state to continue generation from
+func (c *Checkpoint) resumeState(cfg *gen.Config, configHash string, shardIndex int, shardCount int) (*gen.GenerationState, error) {
+	if c.ConfigHash != configHash {
+		return nil, errors.New("checkpoint was made for another config")
+	}
+	if c.ShardIndex != shardIndex || c.ShardCount != shardCount {
+		return nil, errors.Errorf("checkpoint was made for shard %d of %d", c.ShardIndex, c.ShardCount)
+	}
+	if len(c.Entities) != len(cfg.Entities) {
+		return nil, errors.Errorf("checkpoint has %d entities, config has %d", len(c.Entities), len(cfg.Entities))
+	}
+
+	state := &gen.GenerationState{
+		Iteration: c.Iteration,
+		Elapsed:   c.Elapsed,
+		Entities:  make([]gen.EntityState, len(c.Entities)),
+	}
+	for i, entity := range c.Entities {
+		if entity.Filepath != cfg.Entities[i].Config.Filepath {
+			return nil, errors.Errorf("checkpoint entity %d has output %s, config has %s",
+				i, entity.Filepath, cfg.Entities[i].Config.Filepath)
+		}
+		state.Entities[i] = gen.EntityState{
+			Records: entity.Records,
+			Written: entity.Written,
+			Bytes:   entity.Bytes,
+			Elapsed: entity.Elapsed,
+			Fields:  entity.Fields,
+		}
+	}
+	return state, nil
+}
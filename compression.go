@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io"
+	"runtime"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"github.com/pierrec/lz4/v4"
+	"github.com/pkg/errors"
+	"github.com/txix-open/gogen/pkg/gen"
+)
+
+const (
+	gzipBlockSize = 1 << 20
+)
+
+// compressor compresses records of the output, every compressor is able to finish the frame
+// and start a new one, decompressed concatenation of frames is equal to the written records
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// newCompressor returns nil, if the output is not compressed,
+// blocks are compressed in parallel, so the writer goroutine of the entity is not a bottleneck
+func newCompressor(compression string, w io.Writer) (compressor, error) {
+	switch compression {
+	case "", gen.NoCompression:
+		return nil, nil
+	case gen.GzipCompression:
+		gz := pgzip.NewWriter(w)
+		err := gz.SetConcurrency(gzipBlockSize, 2*runtime.GOMAXPROCS(0))
+		if err != nil {
+			return nil, errors.WithMessage(err, "set gzip concurrency")
+		}
+		return gz, nil
+	case gen.ZstdCompression:
+		enc, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(runtime.GOMAXPROCS(0)))
+		if err != nil {
+			return nil, errors.WithMessage(err, "new zstd encoder")
+		}
+		return enc, nil
+	case gen.Lz4Compression:
+		lw := lz4.NewWriter(w)
+		err := lw.Apply(lz4.ConcurrencyOption(runtime.GOMAXPROCS(0)))
+		if err != nil {
+			return nil, errors.WithMessage(err, "set lz4 concurrency")
+		}
+		return lw, nil
+	default:
+		return nil, errors.Errorf("unknown compression %q", compression)
+	}
+}
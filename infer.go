@@ -0,0 +1,555 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/txix-open/gogen/pkg/gen"
+)
+
+const (
+	NdjsonFormat = "ndjson"
+
+	defaultEnumMaxValues = 10
+	weightPrecision      = 10000
+)
+
+var (
+	uuidRegexp  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	emailRegexp = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+	// configJson reads samples and writes inferred configs with sorted keys like generated records
+	configJson = jsoniter.Config{
+		EscapeHTML:                    false,
+		MarshalFloatWith6Digits:       true,
+		ObjectFieldMustBeSimpleString: true,
+		SortMapKeys:                   true,
+	}.Froze()
+
+	inferDateLayouts = []string{
+		time.RFC3339,
+		time.DateTime,
+		"2006-01-02T15:04:05",
+		time.DateOnly,
+		"02.01.2006 15:04:05",
+		"02.01.2006",
+		"2006/01/02",
+	}
+)
+
+type inferOptions struct {
+	input        string
+	output       string
+	format       string
+	csvSeparator string
+	limit        int
+	enumMax      int
+}
+
+func inferCommand(args []string) error {
+	opts := inferOptions{}
+	flags := flag.NewFlagSet("infer", flag.ContinueOnError)
+	flags.SetOutput(os.Stdout)
+	flags.StringVar(&opts.input, "input", "", "sample file path (ndjson or csv)")
+	flags.StringVar(&opts.output, "output", "-", "generated config path, '-' for stdout")
+	flags.StringVar(&opts.format, "format", "", "sample format: ndjson or csv, default is detected by extension")
+	flags.StringVar(&opts.csvSeparator, "csv-separator", "", "csv sample separator")
+	flags.IntVar(&opts.limit, "limit", 0, "max sample records to read, default = 0 - all")
+	flags.IntVar(&opts.enumMax, "enum-max", defaultEnumMaxValues, "max distinct values to treat field as enum")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if opts.input == "" && flags.NArg() > 0 {
+		opts.input = flags.Arg(0)
+	}
+	if opts.input == "" {
+		return errors.New("sample file path is required")
+	}
+	if opts.format == "" {
+		opts.format = NdjsonFormat
+		if strings.EqualFold(filepath.Ext(opts.input), ".csv") {
+			opts.format = gen.CsvFormat
+		}
+	}
+
+	config, err := inferConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	data, err := configJson.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return errors.WithMessage(err, "marshal config")
+	}
+	data = append(data, '\n')
+	if opts.output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(opts.output, data, 0644) // nolint:gosec
+}
+
+func inferConfig(opts inferOptions) (*gen.Config, error) {
+	f, err := os.Open(opts.input)
+	if err != nil {
+		return nil, errors.WithMessage(err, "open sample file")
+	}
+	defer func() { _ = f.Close() }()
+
+	root := newInferNode(opts.enumMax)
+	var count int
+	switch opts.format {
+	case NdjsonFormat:
+		count, err = observeNdjson(f, root, opts.limit)
+	case gen.CsvFormat:
+		count, err = observeCsv(f, root, opts)
+	default:
+		return nil, errors.Errorf("unknown sample format %q", opts.format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.New("empty sample")
+	}
+	if root.objects == 0 {
+		return nil, errors.New("sample records must be objects")
+	}
+
+	outputFormat := ""
+	ext := ".json"
+	if opts.format == gen.CsvFormat {
+		outputFormat = gen.CsvFormat
+		ext = ".csv"
+	}
+	name := strings.TrimSuffix(filepath.Base(opts.input), filepath.Ext(opts.input))
+
+	return &gen.Config{
+		TotalCount: count,
+		Entities: []gen.Entity{{
+			Field: gen.Field{Fields: root.objectFields()},
+			Config: gen.EntityConfig{
+				Filepath:     name + ".generated" + ext,
+				OutputFormat: outputFormat,
+				CsvSeparator: opts.csvSeparator,
+			},
+		}},
+	}, nil
+}
+
+func observeNdjson(r io.Reader, root *inferNode, limit int) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, bufSize), maxSampleLineSize)
+	count := 0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var val any
+		err := configJson.Unmarshal(line, &val)
+		if err != nil {
+			return 0, errors.WithMessagef(err, "unmarshal sample record %d", count+1)
+		}
+		root.observe(val)
+		count++
+		if limit > 0 && count >= limit {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, errors.WithMessage(err, "read sample file")
+	}
+	return count, nil
+}
+
+func observeCsv(r io.Reader, root *inferNode, opts inferOptions) (int, error) {
+	reader := csv.NewReader(bufio.NewReaderSize(r, bufSize))
+	if opts.csvSeparator != "" {
+		reader.Comma = []rune(opts.csvSeparator)[0]
+	}
+	header, err := reader.Read()
+	if err != nil {
+		return 0, errors.WithMessage(err, "read csv header")
+	}
+	for _, column := range header {
+		root.field(column)
+	}
+
+	count := 0
+	for {
+		line, err := reader.Read()
+		switch {
+		case err == io.EOF:
+			return count, nil
+		case err != nil:
+			return 0, errors.WithMessagef(err, "read csv record %d", count+1)
+		}
+		record := make(map[string]any, len(header))
+		for i, column := range header {
+			if i < len(line) {
+				record[column] = parseCsvValue(line[i])
+			}
+		}
+		root.observe(record)
+		count++
+		if opts.limit > 0 && count >= opts.limit {
+			return count, nil
+		}
+	}
+}
+
+func parseCsvValue(s string) any {
+	if s == "" {
+		return nil
+	}
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return float64(v)
+	}
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseBool(s); err == nil {
+		return v
+	}
+	return s
+}
+
+// inferNode accumulates statistics of all values observed at the same path of the sample
+type inferNode struct {
+	enumMax int
+
+	present int
+	objects int
+	arrays  int
+	bools   int
+	numbers int
+	strings int
+
+	fields     map[string]*inferNode
+	fieldOrder []string
+
+	item           *inferNode
+	minLen, maxLen int
+
+	notIntegers    bool
+	minNum, maxNum float64
+
+	uuids          int
+	emails         int
+	dateLayouts    []int
+	minDate        time.Time
+	maxDate        time.Time
+	minStr, maxStr int
+
+	values      map[any]int
+	valueOrder  []any
+	highCardity bool
+}
+
+func newInferNode(enumMax int) *inferNode {
+	return &inferNode{
+		enumMax:     enumMax,
+		values:      make(map[any]int),
+		dateLayouts: make([]int, len(inferDateLayouts)),
+	}
+}
+
+func (n *inferNode) field(name string) *inferNode {
+	if n.fields == nil {
+		n.fields = make(map[string]*inferNode)
+	}
+	child, ok := n.fields[name]
+	if !ok {
+		child = newInferNode(n.enumMax)
+		n.fields[name] = child
+		n.fieldOrder = append(n.fieldOrder, name)
+	}
+	return child
+}
+
+func (n *inferNode) observe(val any) {
+	if val == nil {
+		return
+	}
+	n.present++
+
+	switch v := val.(type) {
+	case map[string]any:
+		n.objects++
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			n.field(key).observe(v[key])
+		}
+	case []any:
+		if n.arrays == 0 || len(v) < n.minLen {
+			n.minLen = len(v)
+		}
+		n.maxLen = max(n.maxLen, len(v))
+		n.arrays++
+		if n.item == nil {
+			n.item = newInferNode(n.enumMax)
+		}
+		for _, item := range v {
+			n.item.observe(item)
+		}
+	case bool:
+		n.bools++
+		n.observeValue(v)
+	case float64:
+		if n.numbers == 0 || v < n.minNum {
+			n.minNum = v
+		}
+		if n.numbers == 0 || v > n.maxNum {
+			n.maxNum = v
+		}
+		n.numbers++
+		n.notIntegers = n.notIntegers || v != math.Trunc(v)
+		n.observeValue(v)
+	case string:
+		n.observeString(v)
+		n.observeValue(v)
+	}
+}
+
+func (n *inferNode) observeString(s string) {
+	length := len([]rune(s))
+	if n.strings == 0 || length < n.minStr {
+		n.minStr = length
+	}
+	n.maxStr = max(n.maxStr, length)
+	n.strings++
+
+	if uuidRegexp.MatchString(s) {
+		n.uuids++
+	}
+	if emailRegexp.MatchString(s) {
+		n.emails++
+	}
+	for i, layout := range inferDateLayouts {
+		date, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		n.dateLayouts[i]++
+		if n.minDate.IsZero() || date.Before(n.minDate) {
+			n.minDate = date
+		}
+		if date.After(n.maxDate) {
+			n.maxDate = date
+		}
+	}
+}
+
+func (n *inferNode) observeValue(val any) {
+	if n.highCardity {
+		return
+	}
+	if _, ok := n.values[val]; !ok {
+		if len(n.values) >= n.enumMax {
+			n.highCardity = true
+			n.values = nil
+			n.valueOrder = nil
+			return
+		}
+		n.valueOrder = append(n.valueOrder, val)
+	}
+	n.values[val]++
+}
+
+func (n *inferNode) objectFields() []gen.Field {
+	fields := make([]gen.Field, 0, len(n.fieldOrder))
+	for _, name := range n.fieldOrder {
+		child := n.fields[name]
+		field := child.toField(n.objects)
+		field.Name = name
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// toField builds field config from observed statistics, parentCount is count of values of enclosing object or array
+func (n *inferNode) toField(parentCount int) gen.Field {
+	field := gen.Field{}
+	if parentCount > 0 && n.present < parentCount {
+		field.NilChance = int(math.Round(float64(parentCount-n.present) / float64(parentCount) * 100))
+		if n.present > 0 {
+			field.NilChance = min(field.NilChance, 99)
+		}
+	}
+
+	switch n.dominantKind() {
+	case inferKindNone:
+		field.Type = &gen.Type{Type: gen.ConstType, Const: ""}
+		field.NilChance = 100
+	case inferKindObject:
+		field.Fields = n.objectFields()
+	case inferKindArray:
+		field.Array = n.toArray()
+	case inferKindBool:
+		field.Type = &gen.Type{Type: gen.BoolType}
+	case inferKindNumber:
+		n.numberField(&field)
+	case inferKindString:
+		n.stringField(&field)
+	}
+	return field
+}
+
+func (n *inferNode) toArray() *gen.Array {
+	arr := &gen.Array{MinLen: n.minLen, MaxLen: n.maxLen}
+	if arr.MaxLen > arr.MinLen {
+		// upper bound of generated length is exclusive
+		arr.MaxLen++
+	}
+	if n.item == nil || n.item.present == 0 {
+		arr.Fixed = []gen.Field{}
+		arr.MinLen, arr.MaxLen = 0, 0
+		return arr
+	}
+	// nil array items are skipped at generating, so item nil chance is meaningless
+	value := n.item.toField(0)
+	arr.Value = &value
+	return arr
+}
+
+func (n *inferNode) numberField(field *gen.Field) {
+	if n.isEnum(n.numbers) {
+		field.OneOfFields = n.enumFields()
+		return
+	}
+	if n.notIntegers {
+		field.Type = &gen.Type{Type: gen.OneOfType, OneOf: n.valueOrder}
+		if n.highCardity {
+			field.Type = &gen.Type{Type: gen.FloatType, Min: n.minNum, Max: n.maxNum}
+		}
+		return
+	}
+	maxValue := n.maxNum
+	if maxValue > n.minNum {
+		maxValue++
+	}
+	field.Type = &gen.Type{Type: gen.IntType, Min: n.minNum, Max: maxValue}
+}
+
+func (n *inferNode) stringField(field *gen.Field) {
+	switch {
+	case n.uuids == n.strings:
+		field.Type = &gen.Type{Type: gen.UuidType}
+	case n.emails == n.strings:
+		field.Type = &gen.Type{Type: gen.EmailType}
+	case n.dateLayout() != "":
+		field.Type = &gen.Type{
+			Type:       gen.DateType,
+			DateFormat: n.dateLayout(),
+			Min:        n.minDate.Format(time.DateOnly),
+			Max:        n.maxDate.AddDate(0, 0, 1).Format(time.DateOnly),
+		}
+	case n.isEnum(n.strings):
+		field.OneOfFields = n.enumFields()
+	default:
+		maxLength := n.maxStr
+		if maxLength > n.minStr {
+			maxLength++
+		}
+		field.Type = &gen.Type{Type: gen.StringType, Min: float64(n.minStr), Max: float64(maxLength)}
+	}
+}
+
+func (n *inferNode) dateLayout() string {
+	for i, count := range n.dateLayouts {
+		if count == n.strings {
+			return inferDateLayouts[i]
+		}
+	}
+	return ""
+}
+
+// isEnum reports whether values are repeated often enough to be described as weighted constants
+func (n *inferNode) isEnum(count int) bool {
+	if n.highCardity || len(n.values) == 0 {
+		return false
+	}
+	return len(n.values) == 1 || len(n.values)*2 <= count
+}
+
+// enumFields weights values by their frequencies, weights are multiples of 1/weightPrecision, which sum up to 1
+func (n *inferNode) enumFields() []gen.Field {
+	total := 0
+	largest := 0
+	for i, val := range n.valueOrder {
+		total += n.values[val]
+		if n.values[val] > n.values[n.valueOrder[largest]] {
+			largest = i
+		}
+	}
+
+	// rare values get the min weight, so the largest weight takes the remainder of rounding and clamping
+	units := make([]int, len(n.valueOrder))
+	sum := 0
+	for i, val := range n.valueOrder {
+		units[i] = max(int(math.Round(float64(n.values[val])/float64(total)*weightPrecision)), 1)
+		sum += units[i]
+	}
+	units[largest] = max(units[largest]+weightPrecision-sum, 1)
+
+	fields := make([]gen.Field, 0, len(n.valueOrder))
+	for i, val := range n.valueOrder {
+		fields = append(fields, gen.Field{
+			Weight: float64(units[i]) / weightPrecision,
+			Type:   &gen.Type{Type: gen.ConstType, Const: val},
+		})
+	}
+	return fields
+}
+
+type inferKind int
+
+const (
+	inferKindNone inferKind = iota
+	inferKindObject
+	inferKindArray
+	inferKindBool
+	inferKindNumber
+	inferKindString
+)
+
+func (n *inferNode) dominantKind() inferKind {
+	kind, count := inferKindNone, 0
+	for _, candidate := range []struct {
+		kind  inferKind
+		count int
+	}{
+		{inferKindObject, n.objects},
+		{inferKindArray, n.arrays},
+		{inferKindBool, n.bools},
+		{inferKindNumber, n.numbers},
+		{inferKindString, n.strings},
+	} {
+		if candidate.count > count {
+			kind, count = candidate.kind, candidate.count
+		}
+	}
+	if kind != inferKindString && n.strings > 0 && count < n.present {
+		// mixed scalar kinds are described as strings
+		return inferKindString
+	}
+	return kind
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestEnumFieldsWeightsSumUpToOne(t *testing.T) {
+	n := newInferNode(100)
+	for range 100_000 {
+		n.observe("common")
+	}
+	for i := range 50 {
+		n.observe("rare" + strconv.Itoa(i))
+	}
+
+	sum := 0.0
+	fields := n.enumFields()
+	for _, field := range fields {
+		if field.Weight < 1.0/weightPrecision {
+			t.Fatalf("weight of %v is %v, expected at least %v", field.Type.Const, field.Weight, 1.0/weightPrecision)
+		}
+		sum += field.Weight
+	}
+	if len(fields) != 51 {
+		t.Fatalf("expected 51 values, got %d", len(fields))
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Fatalf("expected weights summing up to 1, got %v", sum)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/log"
+)
+
+const (
+	TextLogFormat = "text"
+	JsonLogFormat = "json"
+)
+
+var logLevels = map[string]log.Level{
+	"debug": log.DebugLevel,
+	"info":  log.InfoLevel,
+	"warn":  log.WarnLevel,
+	"error": log.ErrorLevel,
+}
+
+func newLogger(level string, format string) (*log.Adapter, error) {
+	logLevel, ok := logLevels[level]
+	if !ok {
+		return nil, errors.Errorf("unknown log level '%s', expected debug, info, warn or error", level)
+	}
+	opts := []log.Option{log.WithLevel(logLevel)}
+	switch format {
+	case TextLogFormat:
+		opts = append(opts, log.WithDevelopmentMode())
+	case JsonLogFormat:
+	default:
+		return nil, errors.Errorf("unknown log format '%s', expected text or json", format)
+	}
+
+	logger, err := log.New(opts...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "new logger")
+	}
+	return logger, nil
+}
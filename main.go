@@ -2,150 +2,519 @@
 package main
 
 import (
-	"bufio"
-	"encoding/csv"
+	"context"
 	"flag"
 	"fmt"
 	"github.com/txix-open/isp-kit/infra"
 	"github.com/txix-open/isp-kit/infra/pprof"
+	"github.com/txix-open/isp-kit/log"
+	"github.com/txix-open/isp-kit/metrics"
 	"io"
+	"math/rand/v2"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/go-playground/validator/v10"
-	io2 "github.com/integration-system/isp-io"
 	"github.com/pkg/errors"
+	"github.com/txix-open/gogen/pkg/gen"
 )
 
 var (
-	configPath = "config.json"
-	forceWrite = false
-	check      = false
-	pprofPort  = 0
+	configPath    = "config.json"
+	configFormat  = ""
+	forceWrite    = false
+	check         = false
+	pprofPort     = 0
+	errorPolicy   = gen.FailFastPolicy
+	removePartial = false
+	seed          uint64
+	checkpoint    = ""
+	resume        = false
+
+	checkpointInterval = time.Minute
+	shardIndex         = 0
+	shardCount         = 1
+	referenceTime      = ""
+	manifestPath       = ""
+	fieldStats         = false
+	progressInterval   = 10 * time.Second
+	logLevel           = "info"
+	logFormat          = TextLogFormat
+
+	// console receives progress and report of the generation, it is stderr, when records are written to stdout
+	console io.Writer = os.Stdout
+	// logger receives diagnostics, it writes to stderr
+	logger *log.Adapter
 )
 
 const (
 	bufSize = 32 * 1024
+	// maxSampleLineSize limits lines of ndjson samples for infer
+	maxSampleLineSize = 64 * 1024 * 1024
+
+	metricsPath = "/internal/metrics"
 )
 
 //nolint:funlen
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		// generation is the default command, so the name is optional
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		err := serveCommand(os.Args[2:])
+		if err != nil {
+			fmt.Printf("serve command: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "mock" {
+		err := mockCommand(os.Args[2:])
+		if err != nil {
+			fmt.Printf("mock command: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "infer" {
+		err := inferCommand(os.Args[2:])
+		if err != nil {
+			fmt.Printf("infer command: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	flag.StringVar(&configPath, "config", "config.json", "config path")
+	flag.StringVar(&configFormat, "format", "", "config format: json, yaml or toml, default is detected by extension")
 	flag.BoolVar(&forceWrite, "force", false, "overwrite previous generated files")
-	flag.BoolVar(&check, "check", false, "validate config")
-	flag.IntVar(&pprofPort, "pprofPort", 0, "pprof port, default = 0 - disabled")
+	flag.BoolVar(&check, "check", false, "validate config without generating")
+	flag.IntVar(&pprofPort, "pprofPort", 0, "port of pprof and metrics, default = 0 - disabled")
+	flag.StringVar(&errorPolicy, "on-error", gen.FailFastPolicy, "error policy: fail-fast, skip-record or max-errors=N")
+	flag.BoolVar(&removePartial, "remove-partial", false, "remove output files if generation is interrupted or failed")
+	flag.Uint64Var(&seed, "seed", 0, "seed of random values to reproduce generation, default = 0 - random seed")
+	flag.StringVar(&checkpoint, "checkpoint", "", "path of the file to periodically save generation state to")
+	flag.DurationVar(&checkpointInterval, "checkpoint-interval", time.Minute, "interval of saving generation state")
+	flag.BoolVar(&resume, "resume", false, "continue generation from the checkpoint, appending to the existing files")
+	flag.IntVar(&shardIndex, "shard-index", 0, "index of the shard from 0 to shard-count - 1")
+	flag.IntVar(&shardCount, "shard-count", 1, "number of shards, which split iterations of TotalCount")
+	flag.StringVar(&referenceTime, "reference-time", "",
+		"RFC3339 time, random dates are generated before, default is the current time")
+	flag.DurationVar(&progressInterval, "progress", 10*time.Second,
+		"interval of progress reports, 0 disables them, they are disabled when records are written to stdout")
+	flag.StringVar(&manifestPath, "manifest", "",
+		"path of the generation manifest, default is manifest.json in the directory of the first output file, 'none' disables it")
+	flag.BoolVar(&fieldStats, "field-stats", false, "collect statistics of fields for the manifest, it slows down generation")
+	flag.StringVar(&logLevel, "log-level", "info", "log level: debug, info, warn or error")
+	flag.StringVar(&logFormat, "log-format", TextLogFormat, "log format: text or json")
 
 	flag.CommandLine.SetOutput(os.Stdout)
 	flag.Parse()
 
-	validate := validator.New()
-	validate.RegisterStructValidation(FieldStructLevelValidation, Field{})
-	validate.RegisterStructValidation(TypeStructLevelValidation, Type{})
-	validate.RegisterStructValidation(ArrayStructLevelValidation, Array{})
-
-	configBytes, err := os.ReadFile(configPath)
+	var err error
+	logger, err = newLogger(logLevel, logFormat)
 	if err != nil {
-		fmt.Printf("error opening config file: %v\n", err)
-		return
+		fmt.Println(err)
+		os.Exit(1)
 	}
-	config := new(Config)
-	err = json.Unmarshal(configBytes, config)
+	defer logger.Sync() //nolint:errcheck
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config, err := gen.Load(configPath, configFormat)
 	if err != nil {
-		fmt.Printf("error unmarshaling config: %v\n", err)
-		return
+		logError(ctx, "load config", err)
+		exit(1)
 	}
 
-	var errList validator.ValidationErrors
-	err = validate.Struct(config)
-	switch {
-	case errors.As(err, &errList):
-		fmt.Println("Config validation errors:")
-		const fieldErrMsg string = "Key: '%s' Error:Field validation for '%s' failed on the '%s' tag: %s\n"
-		for _, err := range errList {
-			fmt.Printf(fieldErrMsg, err.Namespace(), err.Field(), err.Tag(), err.Param())
-		}
-		return
-	case err != nil:
-		fmt.Println(errors.WithMessage(err, "unexpected error"))
-		return
+	if configWritesToStdout(config) {
+		console = os.Stderr
 	}
 
 	if pprofPort != 0 {
 		infraServer := infra.NewServer()
 		pprof.RegisterHandlers("/internal", infraServer)
+		infraServer.Handle(metricsPath, metrics.DefaultRegistry.MetricsHandler())
 		go infraServer.ListenAndServe(fmt.Sprintf(":%d", pprofPort)) //nolint:errcheck
-		fmt.Printf("pprof server listening on http://127.0.0.1:%d/internal/debug/pprof\n", pprofPort)
+		logger.Info(ctx, "infra server listening",
+			log.String("pprof", fmt.Sprintf("http://127.0.0.1:%d/internal/debug/pprof", pprofPort)),
+			log.String("metrics", fmt.Sprintf("http://127.0.0.1:%d%s", pprofPort, metricsPath)))
 	}
 
 	if check {
-		checkCommand(config)
+		err = checkCommand(ctx, config)
+		if err != nil {
+			logError(ctx, "config check", err)
+			exit(1)
+		}
 		return
 	}
 
-	err = generateCommand(config)
+	go func() {
+		<-ctx.Done()
+		// the second signal terminates process immediately
+		stop()
+	}()
+
+	err = generateCommand(ctx, config)
 	if err != nil {
-		fmt.Printf("generate command: %v\n", err)
+		logError(ctx, "generate command", err)
+		exit(1)
 	}
 }
 
-func checkCommand(config *Config) {
-	writers := make([]io.Writer, 0, len(config.Entities))
-	for range config.Entities {
-		writers = append(writers, io.Discard)
+// exit flushes the logger, deferred calls are skipped by os.Exit
+func exit(code int) {
+	_ = logger.Sync()
+	os.Exit(code)
+}
+
+// logError logs every config error with its path and position, other errors are logged as is
+func logError(ctx context.Context, message string, err error) {
+	var configErrs gen.ConfigErrors
+	if !errors.As(err, &configErrs) {
+		logger.Error(ctx, message, log.Any("error", err))
+		return
+	}
+	for _, configErr := range configErrs {
+		fields := []log.Field{log.String("error", configErr.Message)}
+		if configErr.Path != "" {
+			fields = append(fields, log.String("field", configErr.Path))
+		}
+		if configErr.Pos.File != "" {
+			fields = append(fields, log.String("position", configErr.Pos.String()))
+		}
+		logger.Error(ctx, message, fields...)
 	}
+}
 
-	config.TotalCount = 5
-	config.GenerateEntities(writers)
+func checkCommand(ctx context.Context, config *gen.Config) error {
+	problems := config.Check()
+	if len(problems) > 0 {
+		return problems
+	}
+
+	fieldsCount := 0
+	config.WalkFields(func(string, *gen.Field, bool) {
+		fieldsCount++
+	})
+	logger.Info(ctx, "config is valid", log.Int("entities", len(config.Entities)),
+		log.Int("sharedFields", len(config.SharedFields)), log.Int("fields", fieldsCount))
+	return nil
 }
 
-func generateCommand(config *Config) error {
-	pipes := make([]io2.WritePipe, len(config.Entities))
-	defer func() {
-		for _, pipe := range pipes {
-			if pipe != nil {
-				_ = pipe.Close()
+// nolint:funlen,cyclop
+func generateCommand(ctx context.Context, config *gen.Config) error {
+	policy, err := gen.ParseErrorPolicy(errorPolicy)
+	if err != nil {
+		return err
+	}
+	switch {
+	case resume && checkpoint == "":
+		return errors.New("-resume requires -checkpoint")
+	case removePartial && checkpoint != "":
+		return errors.New("-remove-partial can't be used with -checkpoint")
+	case shardCount < 1 || shardIndex < 0 || shardIndex >= shardCount:
+		return errors.Errorf("invalid shard %d of %d, expected -shard-index from 0 to %d", shardIndex, shardCount, shardCount-1)
+	case shardCount > 1 && config.TotalCount == 0:
+		return errors.New("TotalCount is required for sharded generation")
+	case shardCount > 1 && seed == 0 && !resume:
+		return errors.New("-seed is required for sharded generation, all shards must use the same one")
+	}
+	for _, entity := range config.Entities {
+		if checkpoint != "" && gen.IsStreamTarget(entity.Config.Filepath) {
+			return errors.Errorf("-checkpoint is not supported for stream output %s", entity.Config.Filepath)
+		}
+		if entity.Config.IsSplit() && gen.IsStreamTarget(entity.Config.Filepath) {
+			return errors.Errorf("stream output %s can't be split into parts", entity.Config.Filepath)
+		}
+	}
+
+	opts := gen.GenerateOptions{
+		Policy:             policy,
+		Seed:               seed,
+		Now:                time.Now(),
+		CheckpointInterval: checkpointInterval,
+		ShardIndex:         shardIndex,
+		ShardCount:         shardCount,
+		Logger:             logger,
+	}
+	if referenceTime != "" {
+		opts.Now, err = time.Parse(time.RFC3339, referenceTime)
+		if err != nil {
+			return errors.WithMessage(err, "parse -reference-time")
+		}
+	}
+	var (
+		configHash string
+		resumeFrom *Checkpoint
+	)
+	if pprofPort != 0 {
+		opts.Metrics = gen.NewMetrics(metrics.DefaultRegistry)
+	}
+	// records in stdout are usually piped into another tool, progress is not reported then
+	if !configWritesToStdout(config) {
+		opts.Progress, opts.ProgressInterval = printProgress, progressInterval
+	}
+	manifest := resolveManifestPath(manifestPath, config)
+	opts.FieldStats = fieldStats && manifest != ""
+	if checkpoint != "" || manifest != "" {
+		configHash, err = config.Hash()
+		if err != nil {
+			return err
+		}
+	}
+	if shardCount > 1 {
+		// every shard writes own part of entity outputs
+		for i := range config.Entities {
+			conf := &config.Entities[i].Config
+			if !gen.IsStreamTarget(conf.Filepath) {
+				conf.Filepath = shardFilepath(conf.Filepath, shardIndex, shardCount)
 			}
 		}
-	}()
-	var filePerm int
-	if forceWrite {
-		filePerm = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
-	} else {
-		filePerm = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+		if manifest != "" {
+			manifest = shardFilepath(manifest, shardIndex, shardCount)
+		}
+		first, last := config.ShardRange(shardIndex, shardCount)
+		logger.Info(ctx, "generate shard", log.Int("shardIndex", shardIndex), log.Int("shardCount", shardCount),
+			log.Int64("firstIteration", first), log.Int64("lastIteration", last-1))
 	}
+	if resume {
+		resumeFrom, err = readCheckpoint(checkpoint)
+		if err != nil {
+			return err
+		}
+		opts.Resume, err = resumeFrom.resumeState(config, configHash, shardIndex, shardCount)
+		if err != nil {
+			return errors.WithMessagef(err, "resume from %s", checkpoint)
+		}
+		if seed != 0 && seed != resumeFrom.Seed {
+			return errors.Errorf("seed %d differs from checkpoint seed %d", seed, resumeFrom.Seed)
+		}
+		opts.Seed, opts.Now = resumeFrom.Seed, resumeFrom.Now
+		logger.Info(ctx, "resume generation", log.String("checkpoint", checkpoint),
+			log.Int64("iteration", resumeFrom.Iteration))
+	}
+	if opts.Seed == 0 {
+		opts.Seed = rand.Uint64() // nolint:gosec
+	}
+
+	// the manifest is written after generation, so existing one is reported before outputs are created
+	err = checkNewFile(manifest, forceWrite || resume)
+	if err != nil {
+		return errors.WithMessage(err, "manifest")
+	}
+
+	outputs := make([]entityOutput, 0, len(config.Entities))
+	closed := false
+	closeOutputs := func() error {
+		if closed {
+			return nil
+		}
+		closed = true
+		var closeErr error
+		for _, output := range outputs {
+			err := output.Close()
+			if err != nil && closeErr == nil {
+				closeErr = err
+			}
+		}
+		return closeErr
+	}
+	defer closeOutputs() //nolint:errcheck
 
+	opener := newOutputOpener()
 	writers := make([]io.Writer, len(config.Entities))
 	for i := range config.Entities {
-		entity := config.Entities[i]
+		entity := &config.Entities[i]
 		conf := entity.Config
-		f, err := os.OpenFile(conf.Filepath, filePerm, 0755)
-		if err != nil {
-			fmt.Printf("opening %s file: %v\n", conf.Filepath, err)
-			return nil
-		}
 
-		if conf.OutputFormat == CsvFormat {
-			csvWriter := csv.NewWriter(f)
-			if conf.CsvSeparator != "" {
-				csvWriter.Comma = []rune(conf.CsvSeparator)[0]
+		var header []byte
+		if conf.OutputFormat == gen.CsvFormat {
+			header, err = gen.CsvHeader(entity)
+			if err != nil {
+				return errors.WithMessagef(err, "csv header of %s", conf.Filepath)
 			}
+		}
 
-			err := csvWriter.Write(entity.CsvColumns())
+		var output entityOutput
+		switch {
+		case conf.IsSplit() && resumeFrom != nil:
+			state := resumeFrom.Entities[i]
+			output, err = resumePartedOutput(conf, header, state.Parts, state.Offset)
+		case conf.IsSplit():
+			// every part starts with own header
+			output, err = newPartedOutput(conf, header)
+			header = nil
+		case resumeFrom != nil:
+			output, err = reopenOutput(conf.Filepath, resumeFrom.Entities[i].Offset, conf.OutputCompression())
+		default:
+			output, err = opener.open(conf.Filepath, conf.OutputCompression())
+		}
+		if err != nil {
+			return err
+		}
+		outputs = append(outputs, output)
+
+		if header != nil && resumeFrom == nil {
+			_, err := output.Write(header)
 			if err != nil {
-				fmt.Printf("csv write: %v\n", err)
-				return nil
+				return errors.WithMessagef(err, "write csv header to %s", conf.Filepath)
 			}
-			csvWriter.Flush()
 		}
 
-		bufWriter := bufio.NewWriterSize(f, bufSize)
-		pipes[i] = io2.NewWritePipe(bufWriter, f)
-		writers[i] = bufWriter
+		writers[i] = output
+	}
+
+	if checkpoint != "" {
+		opts.Checkpoint = func(state gen.GenerationState) error {
+			cp := &Checkpoint{
+				ConfigHash: configHash,
+				Seed:       opts.Seed,
+				ShardIndex: shardIndex,
+				ShardCount: shardCount,
+				Now:        opts.Now,
+				Iteration:  state.Iteration,
+				Elapsed:    state.Elapsed,
+				Entities:   make([]EntityCheckpoint, len(outputs)),
+			}
+			for i, output := range outputs {
+				// stream outputs are rejected with checkpoints
+				output, _ := output.(syncOutput)
+				offset, err := output.sync()
+				if err != nil {
+					return err
+				}
+				var parts []OutputPart
+				if parted, ok := output.(*partedOutput); ok {
+					parts = parted.checkpointParts()
+				}
+				cp.Entities[i] = EntityCheckpoint{
+					Filepath: config.Entities[i].Config.Filepath,
+					Records:  state.Entities[i].Records,
+					Written:  state.Entities[i].Written,
+					Bytes:    state.Entities[i].Bytes,
+					Offset:   offset,
+					Parts:    parts,
+					Elapsed:  state.Entities[i].Elapsed,
+					Fields:   state.Entities[i].Fields,
+				}
+			}
+			return writeCheckpoint(checkpoint, cp)
+		}
 	}
 
 	now := time.Now()
-	config.GenerateEntities(writers)
-	fmt.Printf("Elapsed time: %v\n", time.Since(now))
+	report, genErr := config.GenerateEntities(ctx, writers, opts)
+	closeErr := closeOutputs()
+	printReport(report, time.Since(now))
 
-	return nil
+	partial := report.Interrupted || genErr != nil || closeErr != nil
+	var manifestErr error
+	if manifest != "" && closeErr == nil && !(removePartial && partial) {
+		manifestErr = writeManifest(manifest, newManifest(config, report, outputs, opts, configHash), forceWrite || resume)
+		if manifestErr == nil {
+			logger.Info(ctx, "manifest written", log.String("path", manifest))
+		}
+	}
+	if removePartial && partial {
+		for _, output := range outputs {
+			file, ok := output.(fileOutput)
+			if !ok {
+				continue
+			}
+			for _, path := range file.paths() {
+				err := os.Remove(path)
+				if err != nil {
+					logger.Error(ctx, "remove partial output", log.String("path", path), log.Any("error", err))
+					continue
+				}
+				logger.Info(ctx, "partial output removed", log.String("path", path))
+			}
+		}
+	}
+
+	switch {
+	case report.Interrupted:
+		return errors.New("generation interrupted")
+	case genErr != nil:
+		return errors.WithMessage(genErr, "generation aborted")
+	case closeErr != nil:
+		return closeErr
+	case manifestErr != nil:
+		return manifestErr
+	case report.Errors > 0:
+		return errors.Errorf("%d records were skipped due to errors", report.Errors)
+	default:
+		return nil
+	}
+}
+
+func printProgress(progress gen.Progress) {
+	line := fmt.Sprintf("Progress: %s, %d iterations", progress.Elapsed.Round(time.Second), progress.Iterations)
+	if progress.TotalIterations > 0 {
+		line += fmt.Sprintf(" of %d (%.1f%%)", progress.TotalIterations,
+			float64(progress.Iterations)*100/float64(progress.TotalIterations))
+	}
+	line += fmt.Sprintf(", %.0f iterations/s", progress.IterationsPerSecond)
+	if progress.ETA > 0 {
+		line += fmt.Sprintf(", ETA %s", progress.ETA)
+	}
+	fmt.Fprintln(console, line)
+	for _, entity := range progress.Entities {
+		records := strconv.FormatInt(entity.Records, 10)
+		if entity.Count > 0 {
+			records += "/" + strconv.FormatInt(entity.Count, 10)
+		}
+		fmt.Fprintf(console, "  %s: %s records, %.0f records/s, %.2f MB/s\n",
+			entity.Filepath, records, entity.RecordsPerSecond, entity.BytesPerSecond/1e6)
+	}
+}
+
+func printReport(report *gen.GenerationReport, elapsed time.Duration) {
+	if report.Interrupted {
+		fmt.Fprintln(console, "Generation interrupted, already generated records are written")
+	}
+	if report.Stopped != "" {
+		fmt.Fprintf(console, "Generation stopped: %s\n", report.Stopped)
+	}
+	fmt.Fprintf(console, "Elapsed time: %v\n", elapsed)
+	fmt.Fprintf(console, "Seed: %d\n", report.Seed)
+	fmt.Fprintf(console, "Iterations: %d\n", report.Iterations)
+	for _, entity := range report.Entities {
+		fmt.Fprintf(console, "  %s: %d records, %d bytes\n", entity.Filepath, entity.Records, entity.Bytes)
+	}
+	if report.Errors == 0 {
+		return
+	}
+
+	kinds := make([]string, 0, len(report.ErrorsByKind))
+	for kind, count := range report.ErrorsByKind {
+		kinds = append(kinds, fmt.Sprintf("%s: %d", kind, count))
+	}
+	slices.Sort(kinds)
+	fmt.Fprintf(console, "Errors: %d (%s)\n", report.Errors, strings.Join(kinds, ", "))
+	for _, sample := range report.ErrorSamples {
+		fmt.Fprintf(console, "  %s\n", sample)
+	}
+	if report.Errors > int64(len(report.ErrorSamples)) {
+		fmt.Fprintf(console, "  ... and %d more\n", report.Errors-int64(len(report.ErrorSamples)))
+	}
+}
+
+// shardFilepath inserts part number of the shard before extensions: users.json -> users-00001-of-00004.json
+func shardFilepath(path string, index int, count int) string {
+	dir, file := filepath.Split(path)
+	name, ext, _ := strings.Cut(file, ".")
+	if ext != "" {
+		ext = "." + ext
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%05d-of-%05d%s", name, index, count, ext))
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	_ "embed"
+	json2 "encoding/json"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/gogen/pkg/gen"
+)
+
+const (
+	manifestFilename = "manifest.json"
+	noManifest       = "none"
+)
+
+var (
+	// version is set by -ldflags "-X 'main.version=...'", the version from .version is used by default
+	version = ""
+	//go:embed .version
+	embeddedVersion string
+)
+
+func appVersion() string {
+	if version != "" {
+		return version
+	}
+	return strings.TrimSpace(embeddedVersion)
+}
+
+// Manifest describes generated outputs, it is written after generation
+type Manifest struct {
+	Version       string
+	ConfigHash    string
+	Seed          uint64
+	ShardIndex    int `json:",omitempty"`
+	ShardCount    int `json:",omitempty"`
+	ReferenceTime time.Time
+	// WallTime includes previous runs before resume
+	WallTime    string
+	Iterations  int64
+	Interrupted bool   `json:",omitempty"`
+	Stopped     string `json:",omitempty"`
+	Entities    []EntityManifest
+}
+
+type EntityManifest struct {
+	Filepath    string
+	Format      string
+	Compression string `json:",omitempty"`
+	Records     int64
+	// Bytes is a size of records before compression, Size is a size of files
+	Bytes    int64
+	Size     int64
+	Sha256   string `json:",omitempty"`
+	WallTime string
+	Parts    []OutputPart     `json:",omitempty"`
+	Fields   []gen.FieldStats `json:",omitempty"`
+}
+
+// resolveManifestPath returns path of -manifest, by default it is manifest.json in the directory of the first output file,
+// empty path disables the manifest
+func resolveManifestPath(path string, cfg *gen.Config) string {
+	switch path {
+	case noManifest:
+		return ""
+	case "":
+		for _, entity := range cfg.Entities {
+			if !gen.IsStreamTarget(entity.Config.Filepath) {
+				return filepath.Join(filepath.Dir(entity.Config.Filepath), manifestFilename)
+			}
+		}
+		return ""
+	default:
+		return path
+	}
+}
+
+// newManifest describes closed outputs of entities
+func newManifest(cfg *gen.Config, report *gen.GenerationReport, outputs []entityOutput, opts gen.GenerateOptions,
+	configHash string) *Manifest {
+	m := &Manifest{
+		Version:       appVersion(),
+		ConfigHash:    configHash,
+		Seed:          report.Seed,
+		ReferenceTime: opts.Now,
+		WallTime:      report.Elapsed.String(),
+		Iterations:    report.Iterations,
+		Interrupted:   report.Interrupted,
+		Stopped:       report.Stopped,
+		Entities:      make([]EntityManifest, len(cfg.Entities)),
+	}
+	if opts.ShardCount > 1 {
+		m.ShardIndex, m.ShardCount = opts.ShardIndex, opts.ShardCount
+	}
+	for i := range cfg.Entities {
+		m.Entities[i] = newEntityManifest(&cfg.Entities[i], report.Entities[i], outputs[i])
+	}
+	return m
+}
+
+func newEntityManifest(entity *gen.Entity, report gen.EntityReport, output entityOutput) EntityManifest {
+	m := EntityManifest{
+		Filepath:    entity.Config.Filepath,
+		Format:      entity.Config.OutputFormat,
+		Compression: entity.Config.OutputCompression(),
+		Records:     report.Records,
+		Bytes:       report.Bytes,
+		WallTime:    report.Elapsed.String(),
+		Fields:      make([]gen.FieldStats, len(report.Fields)),
+	}
+	if m.Format == "" {
+		m.Format = "json"
+	}
+	if m.Compression == gen.NoCompression {
+		m.Compression = ""
+	}
+	for i, field := range report.Fields {
+		field.Sketch = nil
+		m.Fields[i] = field
+	}
+
+	switch output := output.(type) {
+	case *outputFile:
+		m.Size = output.size
+		m.Sha256 = output.checksum()
+	case *partedOutput:
+		m.Parts = output.parts
+		for _, part := range output.parts {
+			m.Size += part.Size
+		}
+	}
+	return m
+}
+
+func writeManifest(path string, manifest *Manifest, overwrite bool) error {
+	b, err := json2.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.WithMessage(err, "marshal manifest")
+	}
+	err = writeNewFile(path, b, overwrite)
+	if err != nil {
+		return errors.WithMessage(err, "write manifest")
+	}
+	return nil
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/gogen/pkg/gen"
+	"github.com/txix-open/isp-kit/infra"
+	"github.com/txix-open/isp-kit/infra/pprof"
+	"github.com/txix-open/isp-kit/log"
+	"github.com/txix-open/isp-kit/metrics"
+)
+
+const (
+	defaultIdField   = "id"
+	defaultPageLimit = 20
+	maxPageLimit     = 1000
+)
+
+// reservedQueryParams are parameters of pagination, other parameters of list requests are filters by fields
+var reservedQueryParams = []string{"limit", "offset"}
+
+type mockOptions struct {
+	configPath   string
+	configFormat string
+	port         int
+	seed         uint64
+}
+
+func mockCommand(args []string) error {
+	opts := mockOptions{}
+	flags := flag.NewFlagSet("mock", flag.ContinueOnError)
+	flags.SetOutput(os.Stdout)
+	flags.StringVar(&opts.configPath, "config", "config.json", "config path")
+	flags.StringVar(&opts.configFormat, "format", "", "config format: json, yaml or toml, default is detected by extension")
+	flags.IntVar(&opts.port, "port", 8080, "http port")
+	flags.Uint64Var(&opts.seed, "seed", 0, "seed of the dataset, default = 0 - random seed")
+	flags.StringVar(&logLevel, "log-level", "info", "log level: debug, info, warn or error")
+	flags.StringVar(&logFormat, "log-format", TextLogFormat, "log format: text or json")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	logger, err = newLogger(logLevel, logFormat)
+	if err != nil {
+		return err
+	}
+	defer logger.Sync() //nolint:errcheck
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config, err := gen.Load(opts.configPath, opts.configFormat)
+	if err != nil {
+		return errors.WithMessage(err, "load config")
+	}
+	if opts.seed == 0 {
+		opts.seed = rand.Uint64() // nolint:gosec
+	}
+	dataset, err := newMockDataset(ctx, config, opts.seed)
+	if err != nil {
+		return errors.WithMessage(err, "generate dataset")
+	}
+	for name, entity := range dataset.entities {
+		logger.Info(ctx, "dataset generated", log.String("entity", name), log.Int("records", len(entity.records)))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{entity}", dataset.list)
+	mux.HandleFunc("POST /{entity}", dataset.create)
+	mux.HandleFunc("GET /{entity}/{id}", dataset.get)
+	mux.HandleFunc("PUT /{entity}/{id}", dataset.replace)
+	mux.HandleFunc("DELETE /{entity}/{id}", dataset.delete)
+	mux.HandleFunc("GET /{entity}/{id}/{related}", dataset.related)
+
+	infraServer := infra.NewServer()
+	pprof.RegisterHandlers("/internal", infraServer)
+	infraServer.Handle(metricsPath, metrics.DefaultRegistry.MetricsHandler())
+	infraServer.Handle("/", mux)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- infraServer.ListenAndServe(fmt.Sprintf(":%d", opts.port))
+	}()
+	logger.Info(ctx, "mock server listening", log.String("address", fmt.Sprintf("http://127.0.0.1:%d", opts.port)),
+		log.Any("seed", opts.seed))
+
+	select {
+	case err := <-serveErr:
+		return errors.WithMessage(err, "listen and serve")
+	case <-ctx.Done():
+		logger.Info(ctx, "mock server stopped")
+		return nil
+	}
+}
+
+// mockDataset is a generated dataset of entities, it is changed by requests
+type mockDataset struct {
+	lock     sync.RWMutex
+	entities map[string]*mockEntity
+}
+
+type mockEntity struct {
+	idField     string
+	foreignKeys map[string]string
+	records     []map[string]any
+	// index is a position of the record by its id
+	index map[string]int
+}
+
+type mockPage struct {
+	Items  []map[string]any `json:"items"`
+	Total  int              `json:"total"`
+	Limit  int              `json:"limit"`
+	Offset int              `json:"offset"`
+}
+
+func newMockDataset(ctx context.Context, cfg *gen.Config, seed uint64) (*mockDataset, error) {
+	ds := &mockDataset{entities: make(map[string]*mockEntity, len(cfg.Entities))}
+	for i := range cfg.Entities {
+		name := cfg.Entities[i].Name()
+		if _, ok := ds.entities[name]; ok {
+			return nil, errors.Errorf("entities share name '%s', set unique 'Name' in their 'Config'", name)
+		}
+		conf := cfg.Entities[i].Config
+		idField := conf.IdField
+		if idField == "" {
+			idField = defaultIdField
+		}
+		ds.entities[name] = &mockEntity{
+			idField:     idField,
+			foreignKeys: conf.ForeignKeys,
+			index:       make(map[string]int),
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := gen.New(cfg, gen.WithSeed(seed), gen.WithLogger(logger)).Stream(ctx)
+	for record := range stream.Records() {
+		value, ok := record.Value.(map[string]any)
+		if !ok {
+			return nil, errors.Errorf("records of entity '%s' are %T, expected objects", record.Entity, record.Value)
+		}
+		ds.entities[record.Entity].add(value)
+	}
+	report, err := stream.Wait()
+	switch {
+	case err != nil:
+		return nil, err
+	case report.Interrupted:
+		return nil, errors.New("generation interrupted")
+	}
+	return ds, nil
+}
+
+// add appends the record, records without ids or with duplicated ids are not indexed
+func (e *mockEntity) add(record map[string]any) {
+	e.records = append(e.records, record)
+	id, ok := recordField(record, e.idField)
+	if !ok {
+		return
+	}
+	if _, ok := e.index[recordKey(id)]; !ok {
+		e.index[recordKey(id)] = len(e.records) - 1
+	}
+}
+
+func (e *mockEntity) reindex() {
+	clear(e.index)
+	records := e.records
+	e.records = make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		e.add(record)
+	}
+}
+
+func (e *mockEntity) find(id string) (map[string]any, bool) {
+	i, ok := e.index[id]
+	if !ok {
+		return nil, false
+	}
+	return e.records[i], true
+}
+
+func (ds *mockDataset) entity(w http.ResponseWriter, r *http.Request) (*mockEntity, bool) {
+	name := r.PathValue("entity")
+	entity, ok := ds.entities[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("entity '%s' not found", name), http.StatusNotFound)
+	}
+	return entity, ok
+}
+
+// list returns a page of records filtered by fields like '?status=active&address.city=Kazan'
+func (ds *mockDataset) list(w http.ResponseWriter, r *http.Request) {
+	ds.lock.RLock()
+	defer ds.lock.RUnlock()
+	entity, ok := ds.entity(w, r)
+	if !ok {
+		return
+	}
+	writePage(w, r, entity.records)
+}
+
+func (ds *mockDataset) get(w http.ResponseWriter, r *http.Request) {
+	ds.lock.RLock()
+	defer ds.lock.RUnlock()
+	entity, ok := ds.entity(w, r)
+	if !ok {
+		return
+	}
+	record, ok := entity.find(r.PathValue("id"))
+	if !ok {
+		http.Error(w, fmt.Sprintf("record '%s' not found", r.PathValue("id")), http.StatusNotFound)
+		return
+	}
+	writeJsonResponse(w, http.StatusOK, record)
+}
+
+// related navigates foreign keys: the referenced record, if the entity references the related one,
+// or a page of records of the related entity, which reference the record
+func (ds *mockDataset) related(w http.ResponseWriter, r *http.Request) {
+	ds.lock.RLock()
+	defer ds.lock.RUnlock()
+	entity, ok := ds.entity(w, r)
+	if !ok {
+		return
+	}
+	id, relatedName := r.PathValue("id"), r.PathValue("related")
+	record, ok := entity.find(id)
+	if !ok {
+		http.Error(w, fmt.Sprintf("record '%s' not found", id), http.StatusNotFound)
+		return
+	}
+	related, ok := ds.entities[relatedName]
+	if !ok {
+		http.Error(w, fmt.Sprintf("entity '%s' not found", relatedName), http.StatusNotFound)
+		return
+	}
+
+	for field, target := range entity.foreignKeys {
+		if target != relatedName {
+			continue
+		}
+		key, _ := recordField(record, field)
+		referenced, ok := related.find(recordKey(key))
+		if !ok {
+			http.Error(w, fmt.Sprintf("referenced record '%s' not found", recordKey(key)), http.StatusNotFound)
+			return
+		}
+		writeJsonResponse(w, http.StatusOK, referenced)
+		return
+	}
+
+	for field, target := range related.foreignKeys {
+		if target != r.PathValue("entity") {
+			continue
+		}
+		records := make([]map[string]any, 0)
+		for _, relatedRecord := range related.records {
+			key, ok := recordField(relatedRecord, field)
+			if ok && recordKey(key) == id {
+				records = append(records, relatedRecord)
+			}
+		}
+		writePage(w, r, records)
+		return
+	}
+
+	http.Error(w, fmt.Sprintf("entities '%s' and '%s' are not related by ForeignKeys", r.PathValue("entity"), relatedName),
+		http.StatusNotFound)
+}
+
+func (ds *mockDataset) create(w http.ResponseWriter, r *http.Request) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	entity, ok := ds.entity(w, r)
+	if !ok {
+		return
+	}
+	record, err := readRecord(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), readRecordStatus(err))
+		return
+	}
+	id, ok := recordField(record, entity.idField)
+	if !ok {
+		http.Error(w, fmt.Sprintf("field '%s' is required", entity.idField), http.StatusBadRequest)
+		return
+	}
+	if _, ok := entity.find(recordKey(id)); ok {
+		http.Error(w, fmt.Sprintf("record '%s' already exists", recordKey(id)), http.StatusConflict)
+		return
+	}
+	entity.add(record)
+	writeJsonResponse(w, http.StatusCreated, record)
+}
+
+func (ds *mockDataset) replace(w http.ResponseWriter, r *http.Request) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	entity, ok := ds.entity(w, r)
+	if !ok {
+		return
+	}
+	i, ok := entity.index[r.PathValue("id")]
+	if !ok {
+		http.Error(w, fmt.Sprintf("record '%s' not found", r.PathValue("id")), http.StatusNotFound)
+		return
+	}
+	record, err := readRecord(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), readRecordStatus(err))
+		return
+	}
+	// the id is kept, so the record stays in the index
+	id, _ := recordField(entity.records[i], entity.idField)
+	err = setRecordField(record, entity.idField, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entity.records[i] = record
+	writeJsonResponse(w, http.StatusOK, record)
+}
+
+func (ds *mockDataset) delete(w http.ResponseWriter, r *http.Request) {
+	ds.lock.Lock()
+	defer ds.lock.Unlock()
+	entity, ok := ds.entity(w, r)
+	if !ok {
+		return
+	}
+	i, ok := entity.index[r.PathValue("id")]
+	if !ok {
+		http.Error(w, fmt.Sprintf("record '%s' not found", r.PathValue("id")), http.StatusNotFound)
+		return
+	}
+	entity.records = slices.Delete(entity.records, i, i+1)
+	entity.reindex()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writePage(w http.ResponseWriter, r *http.Request, records []map[string]any) {
+	query := r.URL.Query()
+	limit, err := queryInt(query.Get("limit"), defaultPageLimit)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		http.Error(w, fmt.Sprintf("invalid limit, expected from 1 to %d", maxPageLimit), http.StatusBadRequest)
+		return
+	}
+	offset, err := queryInt(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid offset, expected non-negative integer", http.StatusBadRequest)
+		return
+	}
+
+	filtered := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		if matchesFilters(record, query) {
+			filtered = append(filtered, record)
+		}
+	}
+	page := mockPage{
+		Items:  filtered[min(offset, len(filtered)):min(offset+limit, len(filtered))],
+		Total:  len(filtered),
+		Limit:  limit,
+		Offset: offset,
+	}
+	writeJsonResponse(w, http.StatusOK, page)
+}
+
+// matchesFilters compares fields of the record with values of the query as strings
+func matchesFilters(record map[string]any, query map[string][]string) bool {
+	for field, values := range query {
+		if slices.Contains(reservedQueryParams, field) {
+			continue
+		}
+		value, ok := recordField(record, field)
+		if !ok || !slices.Contains(values, recordKey(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordField returns a value of the field by its path like 'address.city'
+func recordField(record map[string]any, path string) (any, bool) {
+	var value any = record
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		value, ok = object[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, value != nil
+}
+
+// recordKey formats values of ids and filters, numbers of json bodies are float64 and are formatted without exponent
+func recordKey(value any) string {
+	if f, ok := value.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// setRecordField sets the value of the field by its path like 'meta.id', missing objects of the path are created
+func setRecordField(record map[string]any, path string, value any) error {
+	keys := strings.Split(path, ".")
+	object := record
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := object[key]
+		if !ok || next == nil {
+			next = make(map[string]any)
+			object[key] = next
+		}
+		object, ok = next.(map[string]any)
+		if !ok {
+			return errors.Errorf("field '%s' of '%s' is not an object", key, path)
+		}
+	}
+	object[keys[len(keys)-1]] = value
+	return nil
+}
+
+// readRecord reads the json object of the body, bodies above maxInlineConfigSize are rejected
+func readRecord(w http.ResponseWriter, r *http.Request) (map[string]any, error) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInlineConfigSize))
+	if err != nil {
+		return nil, errors.WithMessage(err, "read record")
+	}
+	record := make(map[string]any)
+	err = responseJson.Unmarshal(data, &record)
+	if err != nil {
+		return nil, errors.WithMessage(err, "expected json object")
+	}
+	return record, nil
+}
+
+// readRecordStatus is 413 for bodies above the limit and 400 for other errors of readRecord
+func readRecordStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func queryInt(value string, defaultValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func writeJsonResponse(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = responseJson.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestMockDataset() *mockDataset {
+	entity := &mockEntity{idField: "meta.id", index: make(map[string]int)}
+	entity.add(map[string]any{"meta": map[string]any{"id": "1"}, "name": "a"})
+	return &mockDataset{entities: map[string]*mockEntity{"users": entity}}
+}
+
+func mockRequest(method string, body string, id string) *http.Request {
+	r := httptest.NewRequest(method, "/users/"+id, strings.NewReader(body))
+	r.SetPathValue("entity", "users")
+	r.SetPathValue("id", id)
+	return r
+}
+
+func TestMockReplaceKeepsNestedId(t *testing.T) {
+	ds := newTestMockDataset()
+
+	w := httptest.NewRecorder()
+	ds.replace(w, mockRequest(http.MethodPut, `{"name": "b"}`, "1"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	ds.get(w, mockRequest(http.MethodGet, "", "1"))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"meta":{"id":"1"}`) {
+		t.Fatalf("expected replaced record with id, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	ds.replace(w, mockRequest(http.MethodPut, `{"meta": 1}`, "1"))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func TestMockRejectsLargeRecords(t *testing.T) {
+	ds := newTestMockDataset()
+
+	body := `{"name": "` + strings.Repeat("a", maxInlineConfigSize) + `"}`
+	w := httptest.NewRecorder()
+	ds.create(w, mockRequest(http.MethodPost, body, ""))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/gogen/pkg/gen"
+)
+
+const stdoutStreamKey = gen.FdTargetPrefix + "1"
+
+// entityOutput is a destination of entity records
+type entityOutput interface {
+	io.Writer
+	Flush() error
+	Close() error
+}
+
+// writesToStdout reports whether the target is stdout, so console messages must be written to stderr
+func writesToStdout(path string) bool {
+	return streamKey(path) == stdoutStreamKey
+}
+
+func configWritesToStdout(cfg *gen.Config) bool {
+	return slices.ContainsFunc(cfg.Entities, func(e gen.Entity) bool { return writesToStdout(e.Config.Filepath) })
+}
+
+// outputOpener opens outputs of entities, entities with the same stream target share it
+type outputOpener struct {
+	streams map[string]*sharedStream
+}
+
+func newOutputOpener() *outputOpener {
+	return &outputOpener{
+		streams: make(map[string]*sharedStream),
+	}
+}
+
+func (o *outputOpener) open(path string, compression string) (entityOutput, error) {
+	if !gen.IsStreamTarget(path) {
+		return createOutput(path, compression, forceWrite)
+	}
+
+	key := streamKey(path)
+	stream, ok := o.streams[key]
+	if ok && stream.compression != compression {
+		return nil, errors.Errorf("entities writing to %s must have the same compression", path)
+	}
+	if !ok {
+		file, owned, err := openStream(path)
+		if err != nil {
+			return nil, err
+		}
+		w, err := newRecordWriter(file, compression)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		stream = &sharedStream{file: file, owned: owned, compression: compression, w: w}
+		o.streams[key] = stream
+	}
+	stream.users++
+	return &streamOutput{stream: stream}, nil
+}
+
+// streamKey identifies the resolved target, so "-" and "fd:1" or different paths of the same pipe share a stream
+func streamKey(path string) string {
+	if path == gen.StdoutTarget {
+		return stdoutStreamKey
+	}
+	if fd, ok := strings.CutPrefix(path, gen.FdTargetPrefix); ok {
+		n, err := strconv.Atoi(fd)
+		if err != nil {
+			return path
+		}
+		return gen.FdTargetPrefix + strconv.Itoa(n)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return absPath
+}
+
+// openStream returns opened stream and whether it must be closed after writing
+func openStream(path string) (*os.File, bool, error) {
+	switch {
+	case streamKey(path) == stdoutStreamKey:
+		return os.Stdout, false, nil
+	case strings.HasPrefix(path, gen.FdTargetPrefix):
+		fd, err := strconv.Atoi(strings.TrimPrefix(path, gen.FdTargetPrefix))
+		if err != nil || fd < 0 {
+			return nil, false, errors.Errorf("invalid file descriptor target %s", path)
+		}
+		file := os.NewFile(uintptr(fd), path)
+		if file == nil {
+			return nil, false, errors.Errorf("invalid file descriptor target %s", path)
+		}
+		_, err = file.Stat()
+		if err != nil {
+			return nil, false, errors.WithMessagef(err, "file descriptor target %s", path)
+		}
+		return file, fd > 2, nil
+	default:
+		// named pipe blocks until it is opened for reading
+		file, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return nil, false, errors.WithMessagef(err, "opening %s", path)
+		}
+		return file, true, nil
+	}
+}
+
+// sharedStream writes every record with a single locked write, so records of entities are not interleaved
+type sharedStream struct {
+	lock        sync.Mutex
+	file        *os.File
+	owned       bool
+	compression string
+	w           *recordWriter
+	users       int
+}
+
+type streamOutput struct {
+	stream *sharedStream
+}
+
+func (o *streamOutput) Write(p []byte) (int, error) {
+	o.stream.lock.Lock()
+	defer o.stream.lock.Unlock()
+	return o.stream.w.Write(p)
+}
+
+func (o *streamOutput) Flush() error {
+	o.stream.lock.Lock()
+	defer o.stream.lock.Unlock()
+	return o.stream.w.Flush()
+}
+
+// Close flushes the stream and closes it after the last entity
+func (o *streamOutput) Close() error {
+	s := o.stream
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.users--
+	if s.users > 0 {
+		return s.w.Flush()
+	}
+	err := s.w.finish()
+	if err != nil {
+		return errors.WithMessagef(err, "flush %s", s.file.Name())
+	}
+	if !s.owned {
+		return nil
+	}
+	err = s.file.Close()
+	if err != nil {
+		return errors.WithMessagef(err, "close %s", s.file.Name())
+	}
+	return nil
+}
+
+// recordWriter buffers records and compresses them, if compression is set
+type recordWriter struct {
+	buf  *bufio.Writer
+	comp compressor
+}
+
+func newRecordWriter(w io.Writer, compression string) (*recordWriter, error) {
+	buf := bufio.NewWriterSize(w, bufSize)
+	comp, err := newCompressor(compression, buf)
+	if err != nil {
+		return nil, err
+	}
+	return &recordWriter{buf: buf, comp: comp}, nil
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	if w.comp != nil {
+		return w.comp.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+func (w *recordWriter) Flush() error {
+	if w.comp != nil {
+		err := w.comp.Flush()
+		if err != nil {
+			return errors.WithMessage(err, "flush compressor")
+		}
+	}
+	return w.buf.Flush()
+}
+
+// finish writes the end of the compressed frame and flushes the buffer
+func (w *recordWriter) finish() error {
+	if w.comp != nil {
+		err := w.comp.Close()
+		if err != nil {
+			return errors.WithMessage(err, "close compressor")
+		}
+	}
+	return w.buf.Flush()
+}
+
+// restart starts a new compressed frame after finish
+func (w *recordWriter) restart() {
+	if w.comp != nil {
+		w.comp.Reset(w.buf)
+	}
+}
+
+// fileOutput is an output of regular files, which are removed, if generation is not completed
+type fileOutput interface {
+	paths() []string
+}
+
+// syncOutput is an output of a file, which state can be saved in a checkpoint
+type syncOutput interface {
+	entityOutput
+	sync() (int64, error)
+}
+
+type outputFile struct {
+	file *os.File
+	w    *recordWriter
+	// size and hash are calculated by written bytes of the file
+	size int64
+	hash hash.Hash
+}
+
+func createOutput(path string, compression string, overwrite bool) (*outputFile, error) {
+	var filePerm int
+	if overwrite {
+		filePerm = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	} else {
+		filePerm = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+	f, err := os.OpenFile(path, filePerm, 0755)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "opening %s file", path)
+	}
+	return newOutputFile(f, compression, 0, sha256.New())
+}
+
+// writeNewFile writes data like createOutput opens outputs, existing file is an error without overwrite
+func writeNewFile(path string, data []byte, overwrite bool) error {
+	filePerm := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		filePerm = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, filePerm, 0644) // nolint:gosec
+	if err != nil {
+		return errors.WithMessagef(err, "opening %s file", path)
+	}
+	_, err = f.Write(data)
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.WithMessagef(err, "write %s", path)
+	}
+	return nil
+}
+
+// checkNewFile reports existing file, which writeNewFile would fail to write without overwrite, empty path is skipped
+func checkNewFile(path string, overwrite bool) error {
+	if path == "" || overwrite {
+		return nil
+	}
+	_, err := os.Stat(path)
+	if err == nil {
+		return errors.Errorf("%s file exists, use -force to overwrite it", path)
+	}
+	return nil
+}
+
+// reopenOutput opens existing output to append records after the offset, the rest of the file is truncated,
+// compressed records are appended as a new frame
+func reopenOutput(path string, offset int64, compression string) (*outputFile, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "opening %s file", path)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.WithMessagef(err, "stat %s", path)
+	}
+	if info.Size() < offset {
+		_ = f.Close()
+		return nil, errors.Errorf("%s is shorter than checkpoint offset %d", path, offset)
+	}
+	err = f.Truncate(offset)
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.WithMessagef(err, "truncate %s to checkpoint offset", path)
+	}
+
+	// checksum of the kept part is restored by reading it, so the file position is moved to the offset
+	hash := sha256.New()
+	_, err = io.Copy(hash, io.NewSectionReader(f, 0, offset))
+	if err == nil {
+		_, err = f.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.WithMessagef(err, "read %s", path)
+	}
+	return newOutputFile(f, compression, offset, hash)
+}
+
+func newOutputFile(f *os.File, compression string, size int64, hash hash.Hash) (*outputFile, error) {
+	o := &outputFile{file: f, size: size, hash: hash}
+	w, err := newRecordWriter(fileWriter{o}, compression)
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.WithMessagef(err, "output %s", f.Name())
+	}
+	o.w = w
+	return o, nil
+}
+
+type fileWriter struct {
+	o *outputFile
+}
+
+func (w fileWriter) Write(p []byte) (int, error) {
+	n, err := w.o.file.Write(p)
+	w.o.size += int64(n)
+	_, _ = w.o.hash.Write(p[:n])
+	return n, err
+}
+
+func (o *outputFile) Write(p []byte) (int, error) {
+	return o.w.Write(p)
+}
+
+func (o *outputFile) Flush() error {
+	return o.w.Flush()
+}
+
+// sync makes written records durable and returns the size of the output,
+// compressed frame is finished, so the output can be truncated to the size on resume
+func (o *outputFile) sync() (int64, error) {
+	err := o.w.finish()
+	if err != nil {
+		return 0, errors.WithMessagef(err, "flush %s", o.file.Name())
+	}
+	o.w.restart()
+	err = o.file.Sync()
+	if err != nil {
+		return 0, errors.WithMessagef(err, "sync %s", o.file.Name())
+	}
+	return o.size, nil
+}
+
+// checksum returns sha256 of the written file, it is complete after Close
+func (o *outputFile) checksum() string {
+	return hex.EncodeToString(o.hash.Sum(nil))
+}
+
+func (o *outputFile) paths() []string {
+	return []string{o.file.Name()}
+}
+
+func (o *outputFile) Close() error {
+	err := o.w.finish()
+	if err != nil {
+		_ = o.file.Close()
+		return errors.WithMessagef(err, "flush %s", o.file.Name())
+	}
+	err = o.file.Close()
+	if err != nil {
+		return errors.WithMessagef(err, "close %s", o.file.Name())
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	json2 "encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/gogen/pkg/gen"
+)
+
+// OutputPart is a file of the entity output, which is split by MaxRecordsPerFile or MaxBytesPerFile
+type OutputPart struct {
+	Path    string
+	Records int64
+	// Bytes is a size of records before compression including csv header, Size is a size of the file
+	Bytes  int64
+	Size   int64
+	Sha256 string `json:",omitempty"`
+}
+
+// PartsManifest lists parts of the entity output, it is written after the last part
+type PartsManifest struct {
+	Filepath string
+	Records  int64
+	Parts    []OutputPart
+}
+
+// partsPattern returns Filepath with the part placeholder, it is added before extensions, if Filepath has none
+func partsPattern(path string) string {
+	if gen.PartPlaceholder.MatchString(path) {
+		return path
+	}
+	dir, file := filepath.Split(path)
+	name, ext, _ := strings.Cut(file, ".")
+	if ext != "" {
+		ext = "." + ext
+	}
+	return filepath.Join(dir, name+"-{part:05d}"+ext)
+}
+
+// partFilepath replaces placeholders like '{part}' and '{part:05d}' with the part number
+func partFilepath(pattern string, part int) string {
+	return gen.PartPlaceholder.ReplaceAllStringFunc(pattern, func(placeholder string) string {
+		width, _ := strconv.Atoi(gen.PartPlaceholder.FindStringSubmatch(placeholder)[1])
+		return fmt.Sprintf("%0*d", width, part)
+	})
+}
+
+// partsManifestPath returns path of the manifest: 'users-{part:05d}.csv.gz' -> 'users-manifest.json'
+func partsManifestPath(pattern string) string {
+	dir, file := filepath.Split(pattern)
+	name, _, _ := strings.Cut(gen.PartPlaceholder.ReplaceAllString(file, "manifest"), ".")
+	return filepath.Join(dir, name+".json")
+}
+
+// partedOutput writes records into a sequence of files, the next file is started before the record,
+// which exceeds limits of the current one, so parts are split between records.
+// Size of compressed data is known only after flushing of the compressor, so maxBytes limits uncompressed Bytes
+type partedOutput struct {
+	pattern     string
+	compression string
+	header      []byte
+	maxRecords  int64
+	maxBytes    int64
+	// overwrite is set on resume, parts after the checkpoint are left by the previous run
+	overwrite bool
+
+	parts   []OutputPart
+	current *outputFile
+}
+
+func newPartedOutput(conf gen.EntityConfig, header []byte) (*partedOutput, error) {
+	o := &partedOutput{
+		pattern:     partsPattern(conf.Filepath),
+		compression: conf.OutputCompression(),
+		header:      header,
+		maxRecords:  conf.MaxRecordsPerFile,
+		maxBytes:    conf.MaxBytesPerFile,
+		overwrite:   forceWrite,
+	}
+	err := o.nextPart()
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// resumePartedOutput continues the last part of the checkpoint from the offset
+func resumePartedOutput(conf gen.EntityConfig, header []byte, parts []OutputPart, offset int64) (*partedOutput, error) {
+	if len(parts) == 0 {
+		return nil, errors.Errorf("checkpoint has no parts of %s", conf.Filepath)
+	}
+	o := &partedOutput{
+		pattern:     partsPattern(conf.Filepath),
+		compression: conf.OutputCompression(),
+		header:      header,
+		maxRecords:  conf.MaxRecordsPerFile,
+		maxBytes:    conf.MaxBytesPerFile,
+		overwrite:   true,
+		parts:       slices.Clone(parts),
+	}
+	current, err := reopenOutput(parts[len(parts)-1].Path, offset, o.compression)
+	if err != nil {
+		return nil, err
+	}
+	o.current = current
+	return o, nil
+}
+
+func (o *partedOutput) nextPart() error {
+	path := partFilepath(o.pattern, len(o.parts))
+	current, err := createOutput(path, o.compression, o.overwrite)
+	if err != nil {
+		return err
+	}
+	o.current = current
+	o.parts = append(o.parts, OutputPart{Path: path})
+	if len(o.header) == 0 {
+		return nil
+	}
+	_, err = current.Write(o.header)
+	if err != nil {
+		return errors.WithMessagef(err, "write header to %s", path)
+	}
+	o.parts[len(o.parts)-1].Bytes += int64(len(o.header))
+	return nil
+}
+
+// closePart closes the current part and saves its size and checksum
+func (o *partedOutput) closePart() error {
+	err := o.current.Close()
+	if err != nil {
+		return err
+	}
+	part := &o.parts[len(o.parts)-1]
+	part.Size = o.current.size
+	part.Sha256 = o.current.checksum()
+	return nil
+}
+
+// Write receives a single record
+func (o *partedOutput) Write(p []byte) (int, error) {
+	part := o.parts[len(o.parts)-1]
+	full := o.maxRecords > 0 && part.Records >= o.maxRecords ||
+		o.maxBytes > 0 && part.Bytes+int64(len(p)) > o.maxBytes
+	if full && part.Records > 0 {
+		err := o.closePart()
+		if err == nil {
+			err = o.nextPart()
+		}
+		if err != nil {
+			return 0, errors.WithMessage(err, "rotate output")
+		}
+	}
+
+	n, err := o.current.Write(p)
+	current := &o.parts[len(o.parts)-1]
+	current.Records++
+	current.Bytes += int64(n)
+	return n, err
+}
+
+func (o *partedOutput) Flush() error {
+	return o.current.Flush()
+}
+
+// sync makes written parts durable and returns the size of the current part
+func (o *partedOutput) sync() (int64, error) {
+	size, err := o.current.sync()
+	if err != nil {
+		return 0, err
+	}
+	o.parts[len(o.parts)-1].Size = size
+	return size, nil
+}
+
+// checkpointParts returns parts of the output, checksum of the current part is restored on resume
+func (o *partedOutput) checkpointParts() []OutputPart {
+	return slices.Clone(o.parts)
+}
+
+func (o *partedOutput) paths() []string {
+	paths := make([]string, 0, len(o.parts)+1)
+	for _, part := range o.parts {
+		paths = append(paths, part.Path)
+	}
+	return append(paths, partsManifestPath(o.pattern))
+}
+
+// Close closes the last part and writes the manifest
+func (o *partedOutput) Close() error {
+	err := o.closePart()
+	if err != nil {
+		return err
+	}
+
+	manifest := PartsManifest{
+		Filepath: o.pattern,
+		Parts:    o.parts,
+	}
+	for _, part := range o.parts {
+		manifest.Records += part.Records
+	}
+	b, err := json2.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.WithMessage(err, "marshal parts manifest")
+	}
+	path := partsManifestPath(o.pattern)
+	err = writeNewFile(path, b, o.overwrite)
+	if err != nil {
+		return errors.WithMessage(err, "write parts manifest")
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package gen
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// nolint:gochecknoinits
+func init() {
+	RegisterType(StringType, newStringType)
+	RegisterType(IntType, newIntType)
+	RegisterType(FloatType, newFloatType)
+	RegisterType(DateType, newDateType)
+	RegisterType(BoolType, newBoolType)
+	RegisterType(UuidType, newUuidType)
+	RegisterType(ConstType, newConstType)
+	RegisterType(OneOfType, newOneOfType)
+	RegisterType(SequenceType, newSequenceType)
+	RegisterType(EmailType, newEmailType)
+	RegisterType(ExternalType, newExternalType)
+	RegisterType(GeoJsonType, newGeoJsonType)
+}
+
+func newStringType(t *Type) (TypeGenerator, error) {
+	errs := validateIntegerMinMax(t)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return TypeGeneratorFunc(t.generateString), nil
+}
+
+func newIntType(t *Type) (TypeGenerator, error) {
+	errs := validateIntegerMinMax(t)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	minValue, maxValue, err := t.getMinMaxIntegers()
+	if err != nil {
+		return nil, errors.WithMessage(err, "get min max integers")
+	}
+	return TypeGeneratorFunc(func(gc *GenContext) (any, error) {
+		return gc.randRange(minValue, maxValue), nil
+	}), nil
+}
+
+// newFloatType generates floats uniformly from [Min, Max), default range is [0, 1)
+func newFloatType(t *Type) (TypeGenerator, error) {
+	errs := validateFloatMinMax(t)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	minValue, maxValue := 0.0, 1.0
+	if t.Min != nil {
+		minValue, _ = t.Min.(float64)
+		maxValue, _ = t.Max.(float64)
+	}
+	return TypeGeneratorFunc(func(gc *GenContext) (any, error) {
+		return minValue + gc.rand.Float64()*(maxValue-minValue), nil
+	}), nil
+}
+
+func newDateType(t *Type) (TypeGenerator, error) {
+	errs := validateDateMinMax(t)
+	if t.DateFormat != "" && !isValidDateFormat(t.DateFormat) {
+		errs = append(errs, ConfigError{Path: "DateFormat", Message: "expected Go layout like '2006-01-02T15:04:05Z07:00'"})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return TypeGeneratorFunc(t.generateDate), nil
+}
+
+func newBoolType(*Type) (TypeGenerator, error) {
+	return TypeGeneratorFunc(func(gc *GenContext) (any, error) {
+		return gc.faker.Bool(), nil
+	}), nil
+}
+
+func newUuidType(*Type) (TypeGenerator, error) {
+	return TypeGeneratorFunc(func(gc *GenContext) (any, error) {
+		return gc.faker.UUID(), nil
+	}), nil
+}
+
+func newEmailType(*Type) (TypeGenerator, error) {
+	return TypeGeneratorFunc(func(gc *GenContext) (any, error) {
+		return gc.faker.Email(), nil
+	}), nil
+}
+
+func newConstType(t *Type) (TypeGenerator, error) {
+	if t.Const == nil {
+		return nil, ConfigErrors{{Path: "Const", Message: "'Const' param is required for 'const' type"}}
+	}
+	return TypeGeneratorFunc(func(*GenContext) (any, error) {
+		return t.Const, nil
+	}), nil
+}
+
+func newOneOfType(t *Type) (TypeGenerator, error) {
+	if len(t.OneOf) == 0 {
+		return nil, ConfigErrors{{Path: "OneOf", Message: "'OneOf' param is required for 'oneof' type"}}
+	}
+	return TypeGeneratorFunc(func(gc *GenContext) (any, error) {
+		i := gc.rand.IntN(len(t.OneOf))
+		return t.OneOf[i], nil
+	}), nil
+}
+
+func newSequenceType(t *Type) (TypeGenerator, error) {
+	errs := validateIntegerMinMax(t)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return TypeGeneratorFunc(t.generateSequence), nil
+}
+
+func newExternalType(t *Type) (TypeGenerator, error) {
+	source := t.ExternalCsvSource
+	if source == nil {
+		return nil, ConfigErrors{{Path: "ExternalCsvSource", Message: "'ExternalCsvSource' param is required for 'external' type"}}
+	}
+	return TypeGeneratorFunc(func(gc *GenContext) (any, error) {
+		// the file is read on the first use, so configs can be validated without sources
+		reader, err := source.ensureReader()
+		if err != nil {
+			return nil, err
+		}
+		return reader.Read(gc.rand, gc.Position(t)), nil
+	}), nil
+}
+
+func newGeoJsonType(t *Type) (TypeGenerator, error) {
+	errs := validateGeoJson(t.GeoJson)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return TypeGeneratorFunc(t.generateGeoJSON), nil
+}
+
+func validateIntegerMinMax(t *Type) ConfigErrors {
+	if (t.Min == nil) != (t.Max == nil) {
+		return ConfigErrors{{Path: "Min", Message: "Min and Max must be set together"}}
+	}
+	if t.Min == nil {
+		return nil
+	}
+
+	errs := make(ConfigErrors, 0)
+	mn, minOk := t.Min.(float64)
+	if !minOk || mn != math.Trunc(mn) {
+		errs = append(errs, ConfigError{Path: "Min", Message: fmt.Sprintf("expected integer for '%s' type", t.Type)})
+	}
+	mx, maxOk := t.Max.(float64)
+	if !maxOk || mx != math.Trunc(mx) {
+		errs = append(errs, ConfigError{Path: "Max", Message: fmt.Sprintf("expected integer for '%s' type", t.Type)})
+	}
+	// zero Max of sequence means unlimited sequence
+	unlimited := t.Type == SequenceType && mx == 0
+	if minOk && maxOk && mx < mn && !unlimited {
+		errs = append(errs, ConfigError{Path: "Max", Message: "'Max' is less than 'Min'"})
+	}
+	return errs
+}
+
+func validateFloatMinMax(t *Type) ConfigErrors {
+	if (t.Min == nil) != (t.Max == nil) {
+		return ConfigErrors{{Path: "Min", Message: "Min and Max must be set together"}}
+	}
+	if t.Min == nil {
+		return nil
+	}
+
+	errs := make(ConfigErrors, 0)
+	mn, minOk := t.Min.(float64)
+	if !minOk {
+		errs = append(errs, ConfigError{Path: "Min", Message: "expected number for 'float' type"})
+	}
+	mx, maxOk := t.Max.(float64)
+	if !maxOk {
+		errs = append(errs, ConfigError{Path: "Max", Message: "expected number for 'float' type"})
+	}
+	if minOk && maxOk && mx < mn {
+		errs = append(errs, ConfigError{Path: "Max", Message: "'Max' is less than 'Min'"})
+	}
+	return errs
+}
+
+func validateDateMinMax(t *Type) ConfigErrors {
+	if (t.Min == nil) != (t.Max == nil) {
+		return ConfigErrors{{Path: "Min", Message: "Min and Max must be set together"}}
+	}
+	if t.Min == nil {
+		return nil
+	}
+
+	errs := make(ConfigErrors, 0)
+	minDate, minErr := parseConfigDate(t.Min)
+	if minErr != nil {
+		errs = append(errs, ConfigError{Path: "Min", Message: minErr.Error()})
+	}
+	maxDate, maxErr := parseConfigDate(t.Max)
+	if maxErr != nil {
+		errs = append(errs, ConfigError{Path: "Max", Message: maxErr.Error()})
+	}
+	if minErr == nil && maxErr == nil && maxDate.Before(minDate) {
+		errs = append(errs, ConfigError{Path: "Max", Message: "'Max' is before 'Min'"})
+	}
+	return errs
+}
+
+func parseConfigDate(value any) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected date string in '%s' format for 'date' type", time.DateOnly)
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+// isValidDateFormat reports whether layout contains elements of Go reference time and can be parsed back
+func isValidDateFormat(layout string) bool {
+	reference := time.Date(2001, time.February, 3, 16, 5, 6, 0, time.UTC)
+	formatted := reference.Format(layout)
+	if formatted == layout {
+		return false
+	}
+	_, err := time.Parse(layout, formatted)
+	return err == nil
+}
@@ -0,0 +1,156 @@
+package gen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	supportedFormats = []string{"", "json", CsvFormat}
+)
+
+// Check statically walks the whole config and reports every problem, which would break or spoil generation
+func (cfg *Config) Check() ConfigErrors {
+	c := &configChecker{
+		cfg:          cfg,
+		sharedFields: make(map[string]*Field, len(cfg.SharedFields)),
+		problems:     make(ConfigErrors, 0),
+	}
+	for i := range cfg.SharedFields {
+		c.sharedFields[cfg.SharedFields[i].Name] = &cfg.SharedFields[i]
+	}
+
+	for i := range cfg.Entities {
+		c.checkEntity(indexConfigPath("Entities", i), &cfg.Entities[i])
+	}
+	// params of types are checked by their factories
+	c.problems = append(c.problems, cfg.buildTypes()...)
+	cfg.WalkFields(func(path string, field *Field, _ bool) {
+		if field.Type != nil {
+			c.checkType(joinConfigPath(path, "Type"), field.Type)
+		}
+	})
+	return c.problems
+}
+
+type configChecker struct {
+	cfg          *Config
+	sharedFields map[string]*Field
+	problems     ConfigErrors
+}
+
+func (c *configChecker) report(path string, format string, args ...any) {
+	c.problems = append(c.problems, ConfigError{
+		Pos:     c.cfg.position(path),
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *configChecker) checkEntity(path string, entity *Entity) {
+	conf := entity.Config
+	configPath := joinConfigPath(path, "Config")
+	if !slices.Contains(supportedFormats, conf.OutputFormat) {
+		c.report(joinConfigPath(configPath, "OutputFormat"), "unknown output format %q", conf.OutputFormat)
+	}
+	if len([]rune(conf.CsvSeparator)) > 1 {
+		c.report(joinConfigPath(configPath, "CsvSeparator"), "expected single character separator")
+	}
+	if conf.OutputFormat == CsvFormat && len(entity.Field.Fields) == 0 && len(entity.Field.OneOfFields) == 0 {
+		c.report(joinConfigPath(path, "Field"), "csv output requires object field with 'Fields' or 'OneOfFields'")
+	}
+	if conf.IsSplit() && IsStreamTarget(conf.Filepath) {
+		c.report(joinConfigPath(configPath, "Filepath"), "stream output can't be split into parts")
+	}
+	if fd, ok := strings.CutPrefix(conf.Filepath, FdTargetPrefix); ok {
+		if n, err := strconv.Atoi(fd); err != nil || n < 0 {
+			c.report(joinConfigPath(configPath, "Filepath"), "invalid file descriptor %q", fd)
+		}
+		return
+	}
+	if conf.Filepath == StdoutTarget {
+		return
+	}
+	dir := filepath.Dir(conf.Filepath)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		c.report(joinConfigPath(configPath, "Filepath"), "output directory '%s' does not exist", dir)
+	}
+}
+
+func (c *configChecker) checkType(path string, t *Type) {
+	if t.Reference != "" {
+		c.checkTemplate(path, t)
+		return
+	}
+
+	if t.Type == ExternalType && t.ExternalCsvSource != nil {
+		c.checkExternalSource(joinConfigPath(path, "ExternalCsvSource"), t.ExternalCsvSource)
+	}
+	nonString := slices.Contains([]string{IntType, FloatType, SequenceType, BoolType}, t.Type) || (t.Type == DateType && t.DateFormat == "")
+	if t.AsJson && !t.AsString && t.Template == "" && nonString {
+		c.report(joinConfigPath(path, "AsJson"), "'AsJson' requires string value, use 'AsString' or 'Template'")
+	}
+	c.checkTemplate(path, t)
+}
+
+func (c *configChecker) checkExternalSource(path string, source *CsvDataSource) {
+	header, err := readCsvHeader(source)
+	if err != nil {
+		c.report(joinConfigPath(path, "Filepath"), "%v", err)
+		return
+	}
+	if !slices.Contains(header, source.TargetField) {
+		c.report(joinConfigPath(path, "TargetField"), "column '%s' not found in '%s', available columns: %s",
+			source.TargetField, source.Filepath, strings.Join(header, ", "))
+	}
+}
+
+// checkTemplate formats sample value of the type and looks for fmt error markers like '%!d(string=...)'
+func (c *configChecker) checkTemplate(path string, t *Type) {
+	if t.Template == "" {
+		return
+	}
+	formatted := fmt.Sprintf(t.Template, c.sampleValue(t))
+	if idx := strings.Index(formatted, "%!"); idx != -1 {
+		marker, _, _ := strings.Cut(formatted[idx:], ")")
+		c.report(joinConfigPath(path, "Template"), "bad template verb: %s)", marker)
+	}
+}
+
+func (c *configChecker) sampleValue(t *Type) any {
+	if t.AsString {
+		return ""
+	}
+	if t.Reference != "" {
+		shared, ok := c.sharedFields[t.Reference]
+		if !ok || shared.Type == nil || shared.Type.Reference != "" {
+			return ""
+		}
+		return c.sampleValue(shared.Type)
+	}
+
+	switch t.Type {
+	case IntType:
+		return 0
+	case FloatType:
+		return 0.0
+	case SequenceType:
+		return int64(0)
+	case BoolType:
+		return false
+	case ConstType, OneOfType:
+		if t.Const != nil {
+			return t.Const
+		}
+		if len(t.OneOf) > 0 {
+			return t.OneOf[0]
+		}
+		return ""
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,29 @@
+package gen
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+const (
+	NoCompression   = "none"
+	GzipCompression = "gzip"
+	ZstdCompression = "zstd"
+	Lz4Compression  = "lz4"
+)
+
+var (
+	compressionExtensions = map[string]string{
+		".gz":  GzipCompression,
+		".zst": ZstdCompression,
+		".lz4": Lz4Compression,
+	}
+)
+
+// OutputCompression returns Compression of the entity or infers it from the extension like '.json.gz'
+func (c EntityConfig) OutputCompression() string {
+	if c.Compression != "" {
+		return c.Compression
+	}
+	return compressionExtensions[strings.ToLower(filepath.Ext(c.Filepath))]
+}
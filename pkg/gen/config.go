@@ -0,0 +1,355 @@
+// nolint:tagliatelle
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	json2 "encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+)
+
+const (
+	CsvFormat = "csv"
+
+	weightsSumTolerance = 1e-3
+)
+
+type Config struct {
+	// Includes are paths of configs relative to the current one, their lists are added before own ones
+	Includes []string `json:",omitempty"`
+	// Definitions are reusable fields, which can be referenced from any field by {"$ref": "name"}
+	Definitions map[string]Field `json:",omitempty" validate:"dive"`
+	// TotalCount, Duration and TargetBytes of entities are stop conditions, generation stops when any is reached
+	TotalCount int    `json:",omitempty" validate:"gte=0"`
+	Duration   string `json:",omitempty"`
+	// RatePerSecond limits iterations per second, RateBurst and RateProfile are optional
+	RatePerSecond float64      `json:",omitempty" validate:"gte=0"`
+	RateBurst     int          `json:",omitempty" validate:"gte=0"`
+	RateProfile   *RateProfile `json:",omitempty"`
+	Alphabets     []Alphabet   `json:",omitempty" validate:"dive"`
+	SharedFields  []Field      `json:",omitempty" validate:"dive"`
+	Entities      []Entity     `validate:"required,gt=0,dive"`
+
+	source *configNode
+}
+
+type Alphabet struct {
+	Name   string `validate:"required"`
+	Values string `validate:"required"`
+}
+
+type CsvDataSource struct {
+	Filepath              string `validate:"required"`
+	TargetField           string `validate:"required"`
+	CsvSeparator          string
+	DisableReadRandomMode bool
+
+	lock   sync.Mutex
+	reader atomic.Pointer[csvReader]
+}
+
+func (s *CsvDataSource) ensureReader() (*csvReader, error) {
+	reader := s.reader.Load()
+	if reader != nil {
+		return reader, nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	reader = s.reader.Load()
+	if reader != nil {
+		return reader, nil
+	}
+
+	reader, err := NewCsvReader(s)
+	if err != nil {
+		return nil, err
+	}
+	s.reader.Store(reader)
+	return reader, nil
+}
+
+type Entity struct {
+	Field           Field
+	Config          EntityConfig
+	csvColumnsCache []string
+	alphabets       map[string]string
+}
+
+func (ent *Entity) WithAlphabets(alphabets map[string]string) {
+	ent.alphabets = alphabets
+}
+
+type EntityConfig struct {
+	// Name identifies the entity in the library and the server, the file name of Filepath without extensions is used by default
+	Name string `json:",omitempty"`
+	// Count and Rate are optional
+	Count int64 `json:",omitempty" validate:"gte=0"`
+	// 1..100; if == 0, default is 100
+	Rate         int    `json:",omitempty" validate:"gte=0,lte=100"`
+	Filepath     string `validate:"required"`
+	OutputFormat string `json:",omitempty"`
+	CsvSeparator string `json:",omitempty"`
+	// Compression is inferred from the extension of Filepath, if it is empty, 'none' disables it
+	Compression string `json:",omitempty" validate:"omitempty,oneof=none gzip zstd lz4"`
+	// MaxRecordsPerFile and MaxBytesPerFile split the output into parts,
+	// Filepath can contain the part number placeholder like 'users-{part:05d}.json'
+	// MaxBytesPerFile limits records of the part before compression, so compressed files are smaller than the limit
+	MaxRecordsPerFile int64 `json:",omitempty" validate:"gte=0"`
+	MaxBytesPerFile   int64 `json:",omitempty" validate:"gte=0"`
+	// TargetBytes stops generation of all entities, when output of the entity reaches the size
+	TargetBytes int64 `json:",omitempty" validate:"gte=0"`
+	// RatePerSecond limits records of the entity written per second, RateBurst and RateProfile are optional.
+	// Entities are generated in shared iterations, so the limit slows down records of all entities
+	RatePerSecond float64      `json:",omitempty" validate:"gte=0"`
+	RateBurst     int          `json:",omitempty" validate:"gte=0"`
+	RateProfile   *RateProfile `json:",omitempty"`
+	// IdField identifies records of the entity in the mock server, 'id' is used by default
+	IdField string `json:",omitempty"`
+	// ForeignKeys are fields of records, which reference records of other entities by their IdField,
+	// keys are fields, values are names of entities
+	ForeignKeys  map[string]string `json:",omitempty"`
+	currentCount int64
+}
+
+// Name returns Name of the entity or the file name of Filepath without extensions and the part placeholder
+func (ent *Entity) Name() string {
+	if ent.Config.Name != "" {
+		return ent.Config.Name
+	}
+	name := PartPlaceholder.ReplaceAllString(filepath.Base(ent.Config.Filepath), "")
+	name, _, _ = strings.Cut(name, ".")
+	return strings.Trim(name, "-_")
+}
+
+// IsSplit reports whether the output is split into parts by MaxRecordsPerFile or MaxBytesPerFile
+func (c EntityConfig) IsSplit() bool {
+	return c.MaxRecordsPerFile > 0 || c.MaxBytesPerFile > 0
+}
+
+// shardTargetBytes splits TargetBytes between shards, so all parts together have the target size
+func (c EntityConfig) shardTargetBytes(shardCount int) int64 {
+	if c.TargetBytes == 0 || shardCount <= 1 {
+		return c.TargetBytes
+	}
+	return (c.TargetBytes + int64(shardCount) - 1) / int64(shardCount)
+}
+
+type Field struct {
+	Name        string  `json:",omitempty"`
+	NilChance   int     `json:",omitempty" validate:"gte=0,lte=100"`
+	Weight      float64 `json:",omitempty" validate:"gte=0,lte=1"`
+	Type        *Type   `json:",omitempty"`
+	Fields      []Field `json:",omitempty" validate:"dive"`
+	Array       *Array  `json:",omitempty"`
+	OneOfFields []Field `json:",omitempty" validate:"dive"`
+
+	// path is a config path of the field for warnings, it is set before generation
+	path string
+}
+
+type Type struct {
+	Type string `json:",omitempty"`
+	// TODO:
+	//  - MaskedString by gofakeit.Generate() or gofakeit.Numerify()
+	//  - Date interval by DateRange()
+	Const             any            `json:",omitempty"`
+	OneOf             []any          `json:",omitempty"`
+	DateFormat        string         `json:",omitempty"`
+	Min               any            `json:",omitempty"`
+	Max               any            `json:",omitempty" validate:"omitempty"`
+	AsString          bool           `json:",omitempty"`
+	AsJson            bool           `json:",omitempty"`
+	ExternalCsvSource *CsvDataSource `json:",omitempty"`
+	Template          string         `json:",omitempty"`
+	Reference         string         `json:",omitempty"`
+	Alphabet          string         `json:",omitempty"`
+	GeoJson           *GeoJson       `json:",omitempty"`
+	// Params are params of custom types registered by RegisterType
+	Params map[string]any `json:",omitempty"`
+
+	// generator is created by the factory of the type, when the config is validated
+	generator TypeGenerator
+	// stride is the max number of calls per record for sequences and circular sources
+	stride int64
+}
+
+type Array struct {
+	Value  *Field
+	Fixed  []Field `json:",omitempty" validate:"dive"`
+	MinLen int
+	MaxLen int `validate:"omitempty,gtefield=MinLen"`
+}
+
+func FieldStructLevelValidation(sl validator.StructLevel) {
+	field, _ := sl.Current().Interface().(Field)
+
+	setCount := 0
+
+	if field.Type != nil {
+		setCount++
+	}
+	if field.Fields != nil {
+		setCount++
+	}
+	if field.Array != nil {
+		setCount++
+	}
+	if field.OneOfFields != nil {
+		setCount++
+	}
+
+	switch {
+	case setCount == 0:
+		sl.ReportError(field.Name, "Struct", "", "missing_one_of_optionals", "Zero optional params set")
+	case setCount > 1:
+		sl.ReportError(field.Name, "Struct", "", "many_optional_params", "More than 1 optional params set")
+	}
+
+	weighted, sum := 0, 0.0
+	for _, f := range field.OneOfFields {
+		if f.Weight > 0 {
+			weighted++
+			sum += f.Weight
+		}
+	}
+	switch {
+	case weighted == 0:
+	case weighted < len(field.OneOfFields):
+		sl.ReportError(field.OneOfFields, "OneOfFields", "", "missing_weight", "Weight must be set for all fields or for none")
+	case math.Abs(sum-1) > weightsSumTolerance:
+		sl.ReportError(field.OneOfFields, "OneOfFields", "", "weights_sum", fmt.Sprintf("sum of weights is %g, expected 1", sum))
+	}
+}
+
+func ArrayStructLevelValidation(sl validator.StructLevel) {
+	arr, _ := sl.Current().Interface().(Array)
+
+	if arr.Value == nil && arr.Fixed == nil {
+		sl.ReportError(arr.Value, "Value", "", "missing_one_of_optionals", "Value or Fixed must be set")
+	}
+}
+
+// ConfigStructLevelValidation checks references between parts of the config
+func ConfigStructLevelValidation(sl validator.StructLevel) {
+	cfg, _ := sl.Current().Interface().(Config)
+
+	alphabets := make(map[string]bool, len(cfg.Alphabets))
+	for _, a := range cfg.Alphabets {
+		alphabets[a.Name] = true
+	}
+	sharedFields := make(map[string]bool, len(cfg.SharedFields))
+	for _, f := range cfg.SharedFields {
+		sharedFields[f.Name] = true
+	}
+
+	if cfg.Duration != "" {
+		duration, err := time.ParseDuration(cfg.Duration)
+		if err != nil || duration <= 0 {
+			sl.ReportError(cfg.Duration, "Duration", "Duration", "duration", "expected positive duration like '2h30m'")
+		}
+	}
+	hasTarget := slices.ContainsFunc(cfg.Entities, func(e Entity) bool {
+		return e.Config.TargetBytes > 0
+	})
+	if cfg.TotalCount == 0 && cfg.Duration == "" && !hasTarget {
+		sl.ReportError(cfg.TotalCount, "TotalCount", "TotalCount", "stop_condition",
+			"TotalCount, Duration or TargetBytes of any entity is required")
+	}
+
+	names := make(map[string]bool, len(cfg.Entities))
+	for i := range cfg.Entities {
+		names[cfg.Entities[i].Name()] = true
+	}
+	for i := range cfg.Entities {
+		keysPath := joinConfigPath(joinConfigPath(indexConfigPath("Entities", i), "Config"), "ForeignKeys")
+		for field, entity := range cfg.Entities[i].Config.ForeignKeys {
+			if !names[entity] {
+				sl.ReportError(entity, fmt.Sprintf("%s[%s]", keysPath, field), "ForeignKeys", "unknown_entity",
+					fmt.Sprintf("entity '%s' is not defined in Entities", entity))
+			}
+		}
+	}
+
+	cfg.WalkFields(func(path string, field *Field, shared bool) {
+		t := field.Type
+		if t == nil {
+			return
+		}
+		typePath := joinConfigPath(path, "Type")
+		if t.Alphabet != "" && !alphabets[t.Alphabet] {
+			sl.ReportError(t.Alphabet, joinConfigPath(typePath, "Alphabet"), "Alphabet", "unknown_alphabet",
+				fmt.Sprintf("alphabet '%s' is not defined in Alphabets", t.Alphabet))
+		}
+		switch {
+		case t.Reference == "":
+		case shared:
+			sl.ReportError(t.Reference, joinConfigPath(typePath, "Reference"), "Reference", "shared_reference",
+				"shared fields can't reference each other")
+		case !sharedFields[t.Reference]:
+			sl.ReportError(t.Reference, joinConfigPath(typePath, "Reference"), "Reference", "unknown_reference",
+				fmt.Sprintf("shared field '%s' is not defined in SharedFields", t.Reference))
+		}
+	})
+}
+
+// duration returns parsed Duration, which is validated in ConfigStructLevelValidation
+func (cfg *Config) duration() time.Duration {
+	duration, _ := time.ParseDuration(cfg.Duration)
+	return duration
+}
+
+// WalkFields calls fn for every field of shared fields and entities including nested ones
+func (cfg *Config) WalkFields(fn func(path string, field *Field, shared bool)) {
+	for i := range cfg.SharedFields {
+		walkField(indexConfigPath("SharedFields", i), &cfg.SharedFields[i], func(path string, field *Field) {
+			fn(path, field, true)
+		})
+	}
+	for i := range cfg.Entities {
+		walkField(joinConfigPath(indexConfigPath("Entities", i), "Field"), &cfg.Entities[i].Field, func(path string, field *Field) {
+			fn(path, field, false)
+		})
+	}
+}
+
+func walkField(path string, field *Field, fn func(path string, field *Field)) {
+	fn(path, field)
+	for i := range field.Fields {
+		walkField(indexConfigPath(joinConfigPath(path, "Fields"), i), &field.Fields[i], fn)
+	}
+	for i := range field.OneOfFields {
+		walkField(indexConfigPath(joinConfigPath(path, "OneOfFields"), i), &field.OneOfFields[i], fn)
+	}
+	if arr := field.Array; arr != nil {
+		arrayPath := joinConfigPath(path, "Array")
+		if arr.Value != nil {
+			walkField(joinConfigPath(arrayPath, "Value"), arr.Value, fn)
+		}
+		for i := range arr.Fixed {
+			walkField(indexConfigPath(joinConfigPath(arrayPath, "Fixed"), i), &arr.Fixed[i], fn)
+		}
+	}
+}
+
+// Hash returns checksum of the config, which identifies it in checkpoints and manifests
+func (cfg *Config) Hash() (string, error) {
+	// encoding/json sorts map keys, so equal configs have equal hashes
+	b, err := json2.Marshal(cfg)
+	if err != nil {
+		return "", errors.WithMessage(err, "marshal config")
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
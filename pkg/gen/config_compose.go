@@ -0,0 +1,319 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	includesKey    = "Includes"
+	definitionsKey = "Definitions"
+	refKey         = "$ref"
+	refPrefix      = "#/Definitions/"
+)
+
+var envRegexp = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::([^}]*))?}`)
+
+// loadConfigTree reads config file with all its includes, env placeholders and definition references resolved
+func loadConfigTree(path string, format string) (*configNode, error) {
+	root, err := loadConfigWithIncludes(path, format, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := refResolver{
+		definitions: root.field(definitionsKey),
+		resolved:    make(map[string]*configNode),
+	}
+	return resolver.resolve(root, nil)
+}
+
+// parseConfigTree parses the config without includes and env placeholders like a config received by network
+func parseConfigTree(name string, data []byte, format string) (*configNode, error) {
+	root, err := parseConfigData(name, data, format)
+	if err != nil {
+		return nil, err
+	}
+	if root.kind != objectNode {
+		return nil, ConfigError{Pos: root.pos, Message: "config must be an object"}
+	}
+	if includes := root.field(includesKey); includes != nil {
+		return nil, ConfigError{Pos: includes.pos, Path: includesKey, Message: "includes are not allowed"}
+	}
+
+	resolver := refResolver{
+		definitions: root.field(definitionsKey),
+		resolved:    make(map[string]*configNode),
+	}
+	return resolver.resolve(root, nil)
+}
+
+func loadConfigWithIncludes(path string, format string, stack []string) (*configNode, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "resolve config path")
+	}
+	if slices.Contains(stack, absPath) {
+		chain := append(slices.Clone(stack[slices.Index(stack, absPath):]), absPath)
+		return nil, errors.Errorf("include cycle: %s", strings.Join(chain, " -> "))
+	}
+	stack = append(stack, absPath)
+
+	root, err := parseConfigFile(path, format)
+	if err != nil {
+		return nil, err
+	}
+	if root.kind != objectNode {
+		return nil, ConfigError{Pos: root.pos, Message: "config must be an object"}
+	}
+	err = substituteEnv(root)
+	if err != nil {
+		return nil, err
+	}
+
+	includes := root.field(includesKey)
+	if includes == nil {
+		return root, nil
+	}
+	if includes.kind != arrayNode {
+		return nil, ConfigError{Pos: includes.pos, Path: includesKey, Message: "expected list of file paths"}
+	}
+	for i, include := range includes.items {
+		includePath, ok := include.value.(string)
+		if !ok || includePath == "" {
+			return nil, ConfigError{Pos: include.pos, Path: indexConfigPath(includesKey, i), Message: "expected file path"}
+		}
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(path), includePath)
+		}
+		included, err := loadConfigWithIncludes(includePath, "", stack)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "%s: include '%s'", include.pos, includePath)
+		}
+		mergeIncludedConfig(root, included)
+	}
+	return root, nil
+}
+
+// mergeIncludedConfig adds included lists before own ones, own definitions and values have priority over included
+func mergeIncludedConfig(root *configNode, included *configNode) {
+	for _, key := range included.keys {
+		if key == includesKey {
+			continue
+		}
+		value := included.fields[key]
+		current, ok := root.fields[key]
+		switch {
+		case !ok:
+			root.set(key, value)
+		case current.kind == arrayNode && value.kind == arrayNode:
+			current.items = append(slices.Clone(value.items), current.items...)
+		case current.kind == objectNode && value.kind == objectNode:
+			for _, k := range value.keys {
+				if _, ok := current.fields[k]; !ok {
+					current.set(k, value.fields[k])
+				}
+			}
+		}
+	}
+}
+
+// envKeys are keys, which values can contain env placeholders, true means a numeric value
+var envKeys = map[string]bool{
+	includesKey:         false,
+	"Filepath":          false,
+	"Duration":          false,
+	"TotalCount":        true,
+	"Count":             true,
+	"Rate":              true,
+	"RatePerSecond":     true,
+	"RateBurst":         true,
+	"MaxRecordsPerFile": true,
+	"MaxBytesPerFile":   true,
+	"TargetBytes":       true,
+}
+
+// substituteEnv replaces ${NAME} and ${NAME:default} placeholders in values of paths and counts listed in envKeys,
+// '$$' is an escaped '$', other values like templates and consts are kept as is
+func substituteEnv(n *configNode) error {
+	switch n.kind {
+	case objectNode:
+		for _, key := range n.keys {
+			value := n.fields[key]
+			numeric, ok := envKeys[key]
+			var err error
+			switch {
+			case ok && value.kind == arrayNode:
+				for _, item := range value.items {
+					err = substituteEnvValue(item, numeric)
+					if err != nil {
+						break
+					}
+				}
+			case ok:
+				err = substituteEnvValue(value, numeric)
+			default:
+				err = substituteEnv(value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case arrayNode:
+		for _, item := range n.items {
+			err := substituteEnv(item)
+			if err != nil {
+				return err
+			}
+		}
+	case scalarNode:
+	}
+	return nil
+}
+
+func substituteEnvValue(n *configNode, numeric bool) error {
+	s, ok := n.value.(string)
+	if n.kind != scalarNode || !ok || !strings.Contains(s, "$") {
+		return nil
+	}
+	value, err := expandEnv(s, numeric)
+	if err != nil {
+		return ConfigError{Pos: n.pos, Message: err.Error()}
+	}
+	n.value = value
+	return nil
+}
+
+func expandEnv(s string, numeric bool) (any, error) {
+	var missing []string
+	result := envRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		groups := envRegexp.FindStringSubmatch(match)
+		value, ok := os.LookupEnv(groups[1])
+		switch {
+		case ok:
+			return value
+		case strings.Contains(match, ":"):
+			return groups[2]
+		default:
+			missing = append(missing, groups[1])
+			return match
+		}
+	})
+	if len(missing) > 0 {
+		return nil, errors.Errorf("environment variable %s is not set and has no default", strings.Join(missing, ", "))
+	}
+
+	// numeric values like "${COUNT:100}" are converted to numbers, so they are decoded like numbers in the config
+	if numeric {
+		if i, err := strconv.ParseInt(result, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(result, 64); err == nil {
+			return f, nil
+		}
+	}
+	return result, nil
+}
+
+type refResolver struct {
+	definitions *configNode
+	resolved    map[string]*configNode
+}
+
+// resolve replaces objects with '$ref' key by copy of referenced definition, sibling keys override definition ones
+func (r *refResolver) resolve(n *configNode, stack []string) (*configNode, error) {
+	switch n.kind {
+	case objectNode:
+		if ref, ok := n.fields[refKey]; ok {
+			return r.resolveRef(n, ref, stack)
+		}
+		for _, key := range n.keys {
+			value, err := r.resolve(n.fields[key], stack)
+			if err != nil {
+				return nil, err
+			}
+			n.fields[key] = value
+		}
+	case arrayNode:
+		for i, item := range n.items {
+			value, err := r.resolve(item, stack)
+			if err != nil {
+				return nil, err
+			}
+			n.items[i] = value
+		}
+	case scalarNode:
+	}
+	return n, nil
+}
+
+func (r *refResolver) resolveRef(n *configNode, ref *configNode, stack []string) (*configNode, error) {
+	name, ok := ref.value.(string)
+	if !ok {
+		return nil, ConfigError{Pos: ref.pos, Message: "'$ref' must be a definition name"}
+	}
+	name = strings.TrimPrefix(name, refPrefix)
+	if slices.Contains(stack, name) {
+		chain := append(slices.Clone(stack[slices.Index(stack, name):]), name)
+		return nil, ConfigError{Pos: ref.pos, Message: "definition cycle: " + strings.Join(chain, " -> ")}
+	}
+
+	definition, ok := r.resolved[name]
+	if !ok {
+		raw := r.definitions.field(name)
+		if raw == nil {
+			return nil, ConfigError{Pos: ref.pos, Message: "unknown definition '" + name + "'"}
+		}
+		var err error
+		definition, err = r.resolve(raw, append(stack, name))
+		if err != nil {
+			return nil, err
+		}
+		r.definitions.fields[name] = definition
+		r.resolved[name] = definition
+	}
+	if definition.kind != objectNode {
+		return nil, ConfigError{Pos: ref.pos, Message: "definition '" + name + "' must be an object"}
+	}
+
+	result := definition.clone()
+	result.pos = n.pos
+	for _, key := range n.keys {
+		if key == refKey {
+			continue
+		}
+		value, err := r.resolve(n.fields[key], stack)
+		if err != nil {
+			return nil, err
+		}
+		result.set(key, value)
+	}
+	return result, nil
+}
+
+func (n *configNode) clone() *configNode {
+	result := *n
+	if n.fields != nil {
+		result.keys = slices.Clone(n.keys)
+		result.fields = make(map[string]*configNode, len(n.fields))
+		for key, value := range n.fields {
+			result.fields[key] = value.clone()
+		}
+	}
+	if n.items != nil {
+		result.items = make([]*configNode, len(n.items))
+		for i, item := range n.items {
+			result.items[i] = item.clone()
+		}
+	}
+	return &result
+}
@@ -0,0 +1,641 @@
+package gen
+
+import (
+	"bytes"
+	json2 "encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	JsonConfigFormat = "json"
+	YamlConfigFormat = "yaml"
+	TomlConfigFormat = "toml"
+)
+
+var yamlLineRegexp = regexp.MustCompile(`line (\d+)`)
+
+type sourcePos struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p sourcePos) String() string {
+	switch {
+	case p.Line == 0:
+		return p.File
+	case p.Column == 0:
+		return fmt.Sprintf("%s:%d", p.File, p.Line)
+	default:
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+	}
+}
+
+// ConfigError describes single config problem located in the source file
+type ConfigError struct {
+	Pos     sourcePos
+	Path    string
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	var b strings.Builder
+	if e.Pos.File != "" {
+		b.WriteString(e.Pos.String())
+		b.WriteString(": ")
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+type configNodeKind int
+
+const (
+	scalarNode configNodeKind = iota
+	objectNode
+	arrayNode
+)
+
+// configNode is a format independent config tree which remembers source positions of values
+type configNode struct {
+	kind   configNodeKind
+	pos    sourcePos
+	keys   []string
+	fields map[string]*configNode
+	items  []*configNode
+	value  any
+}
+
+func newObjectNode(pos sourcePos) *configNode {
+	return &configNode{kind: objectNode, pos: pos, fields: make(map[string]*configNode)}
+}
+
+func (n *configNode) set(key string, value *configNode) {
+	if _, ok := n.fields[key]; !ok {
+		n.keys = append(n.keys, key)
+	}
+	n.fields[key] = value
+}
+
+func (n *configNode) field(key string) *configNode {
+	if n == nil || n.kind != objectNode {
+		return nil
+	}
+	if value, ok := n.fields[key]; ok {
+		return value
+	}
+	// decoding is case-insensitive, so lookup is too
+	for _, k := range n.keys {
+		if strings.EqualFold(k, key) {
+			return n.fields[k]
+		}
+	}
+	return nil
+}
+
+func (n *configNode) toValue() any {
+	switch n.kind {
+	case objectNode:
+		m := make(map[string]any, len(n.keys))
+		for _, key := range n.keys {
+			m[key] = n.fields[key].toValue()
+		}
+		return m
+	case arrayNode:
+		arr := make([]any, len(n.items))
+		for i, item := range n.items {
+			arr[i] = item.toValue()
+		}
+		return arr
+	default:
+		return n.value
+	}
+}
+
+// lookup returns the deepest existing node on the path like 'Entities[0].Field.Fields[1]'
+func (n *configNode) lookup(path string) *configNode {
+	current := n
+	for _, segment := range splitConfigPath(path) {
+		var next *configNode
+		switch {
+		case current.kind == arrayNode:
+			i, err := strconv.Atoi(segment)
+			if err == nil && i >= 0 && i < len(current.items) {
+				next = current.items[i]
+			}
+		default:
+			next = current.field(segment)
+		}
+		if next == nil {
+			return current
+		}
+		current = next
+	}
+	return current
+}
+
+func splitConfigPath(path string) []string {
+	segments := make([]string, 0)
+	for _, part := range strings.Split(path, ".") {
+		name, rest, _ := strings.Cut(part, "[")
+		if name != "" {
+			segments = append(segments, name)
+		}
+		for rest != "" {
+			var index string
+			index, rest, _ = strings.Cut(rest, "]")
+			segments = append(segments, index)
+			rest = strings.TrimPrefix(rest, "[")
+		}
+	}
+	return segments
+}
+
+func joinConfigPath(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func indexConfigPath(parent string, i int) string {
+	return fmt.Sprintf("%s[%d]", parent, i)
+}
+
+func detectConfigFormat(path string, format string) (string, error) {
+	if format != "" {
+		format = strings.ToLower(format)
+		if format == "yml" {
+			format = YamlConfigFormat
+		}
+		if !slices.Contains([]string{JsonConfigFormat, YamlConfigFormat, TomlConfigFormat}, format) {
+			return "", errors.Errorf("unknown config format %q", format)
+		}
+		return format, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YamlConfigFormat, nil
+	case ".toml":
+		return TomlConfigFormat, nil
+	default:
+		return JsonConfigFormat, nil
+	}
+}
+
+func parseConfigFile(path string, format string) (*configNode, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "read config file")
+	}
+	return parseConfigData(path, data, format)
+}
+
+// parseConfigData parses the config, path is used to detect format and in positions of errors
+func parseConfigData(path string, data []byte, format string) (*configNode, error) {
+	format, err := detectConfigFormat(path, format)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case YamlConfigFormat:
+		return parseYamlConfig(path, data)
+	case TomlConfigFormat:
+		return parseTomlConfig(path, data)
+	default:
+		return parseJsonConfig(path, data)
+	}
+}
+
+func loadConfig(path string, format string) (*Config, error) {
+	root, err := loadConfigTree(path, format)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConfig(root)
+}
+
+func decodeConfig(root *configNode) (*Config, error) {
+	errs := checkConfigKeys(root)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	data, err := json.Marshal(root.toValue())
+	if err != nil {
+		return nil, errors.WithMessage(err, "marshal config tree")
+	}
+	config := new(Config)
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		return nil, ConfigError{Pos: sourcePos{File: root.pos.File}, Message: err.Error()}
+	}
+	config.source = root
+	return config, nil
+}
+
+// position returns source position of the value on the path or of its nearest existing parent
+func (cfg *Config) position(path string) sourcePos {
+	if cfg.source == nil {
+		return sourcePos{}
+	}
+	return cfg.source.lookup(path).pos
+}
+
+func validateConfig(validate *validator.Validate, config *Config) error {
+	var errList validator.ValidationErrors
+	err := validate.Struct(config)
+	switch {
+	case errors.As(err, &errList):
+		result := make(ConfigErrors, 0, len(errList))
+		for _, err := range errList {
+			_, path, _ := strings.Cut(err.Namespace(), ".")
+			segments := splitConfigPath(path)
+			message := fmt.Sprintf("field validation for '%s' failed on the '%s' tag", segments[len(segments)-1], err.Tag())
+			if err.Param() != "" {
+				message += ": " + err.Param()
+			}
+			result = append(result, ConfigError{Pos: config.position(path), Path: path, Message: message})
+		}
+		return result
+	case err != nil:
+		return errors.WithMessage(err, "unexpected error")
+	default:
+		return nil
+	}
+}
+
+type jsonConfigParser struct {
+	file       string
+	data       []byte
+	lineStarts []int
+	dec        *json2.Decoder
+}
+
+func parseJsonConfig(file string, data []byte) (*configNode, error) {
+	dec := json2.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	p := &jsonConfigParser{
+		file:       file,
+		data:       data,
+		lineStarts: lineStarts(data),
+		dec:        dec,
+	}
+
+	root, err := p.parseValue()
+	if err == nil {
+		_, err = dec.Token()
+		if err == nil {
+			err = errors.New("unexpected data after top-level value")
+		} else if errors.Is(err, io.EOF) {
+			return root, nil
+		}
+	}
+
+	offset := dec.InputOffset()
+	var syntaxErr *json2.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		err = errors.New("unexpected end of JSON input")
+	}
+	return nil, ConfigError{Pos: p.pos(offset), Message: err.Error()}
+}
+
+func (p *jsonConfigParser) parseValue() (*configNode, error) {
+	pos := p.pos(p.nextTokenOffset())
+	token, err := p.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := token.(type) {
+	case json2.Delim:
+		if t == '[' {
+			node := &configNode{kind: arrayNode, pos: pos, items: make([]*configNode, 0)}
+			for p.dec.More() {
+				item, err := p.parseValue()
+				if err != nil {
+					return nil, err
+				}
+				node.items = append(node.items, item)
+			}
+			_, err = p.dec.Token()
+			return node, err
+		}
+
+		node := newObjectNode(pos)
+		for p.dec.More() {
+			keyPos := p.pos(p.nextTokenOffset())
+			token, err := p.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := token.(string)
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			value.pos = keyPos
+			node.set(key, value)
+		}
+		_, err = p.dec.Token()
+		return node, err
+	case json2.Number:
+		if i, err := t.Int64(); err == nil {
+			return &configNode{pos: pos, value: i}, nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &configNode{pos: pos, value: f}, nil
+	default:
+		return &configNode{pos: pos, value: t}, nil
+	}
+}
+
+func (p *jsonConfigParser) nextTokenOffset() int64 {
+	offset := p.dec.InputOffset()
+	for offset < int64(len(p.data)) {
+		switch p.data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+func (p *jsonConfigParser) pos(offset int64) sourcePos {
+	return offsetToPos(p.file, p.lineStarts, int(offset))
+}
+
+func lineStarts(data []byte) []int {
+	starts := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+func offsetToPos(file string, lineStarts []int, offset int) sourcePos {
+	line := sort.SearchInts(lineStarts, offset+1) - 1
+	return sourcePos{File: file, Line: line + 1, Column: offset - lineStarts[line] + 1}
+}
+
+func parseYamlConfig(file string, data []byte) (*configNode, error) {
+	doc := new(yaml.Node)
+	err := yaml.Unmarshal(data, doc)
+	if err != nil {
+		pos := sourcePos{File: file}
+		if match := yamlLineRegexp.FindStringSubmatch(err.Error()); match != nil {
+			pos.Line, _ = strconv.Atoi(match[1])
+		}
+		return nil, ConfigError{Pos: pos, Message: err.Error()}
+	}
+	if len(doc.Content) == 0 {
+		return nil, ConfigError{Pos: sourcePos{File: file}, Message: "empty yaml document"}
+	}
+	return yamlToConfigNode(file, doc.Content[0])
+}
+
+func yamlToConfigNode(file string, n *yaml.Node) (*configNode, error) {
+	pos := sourcePos{File: file, Line: n.Line, Column: n.Column}
+	switch n.Kind {
+	case yaml.AliasNode:
+		node, err := yamlToConfigNode(file, n.Alias)
+		if err != nil {
+			return nil, err
+		}
+		node.pos = pos
+		return node, nil
+	case yaml.MappingNode:
+		node := newObjectNode(pos)
+		merged := make([]*configNode, 0)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			child, err := yamlToConfigNode(file, value)
+			if err != nil {
+				return nil, err
+			}
+			if key.Tag == "!!merge" {
+				merged = append(merged, child)
+				continue
+			}
+			child.pos = sourcePos{File: file, Line: key.Line, Column: key.Column}
+			node.set(key.Value, child)
+		}
+		for _, m := range merged {
+			mergeYamlNode(node, m)
+		}
+		return node, nil
+	case yaml.SequenceNode:
+		node := &configNode{kind: arrayNode, pos: pos, items: make([]*configNode, 0, len(n.Content))}
+		for _, item := range n.Content {
+			child, err := yamlToConfigNode(file, item)
+			if err != nil {
+				return nil, err
+			}
+			node.items = append(node.items, child)
+		}
+		return node, nil
+	case yaml.ScalarNode:
+		if n.Tag == "!!timestamp" {
+			return &configNode{pos: pos, value: n.Value}, nil
+		}
+		var value any
+		err := n.Decode(&value)
+		if err != nil {
+			return nil, ConfigError{Pos: pos, Message: err.Error()}
+		}
+		return &configNode{pos: pos, value: value}, nil
+	default:
+		return nil, ConfigError{Pos: pos, Message: "unexpected yaml node"}
+	}
+}
+
+// mergeYamlNode applies '<<' merge key, explicitly defined keys have priority over merged ones
+func mergeYamlNode(node *configNode, merged *configNode) {
+	switch merged.kind {
+	case objectNode:
+		for _, key := range merged.keys {
+			if _, ok := node.fields[key]; !ok {
+				node.set(key, merged.fields[key])
+			}
+		}
+	case arrayNode:
+		for _, item := range merged.items {
+			mergeYamlNode(node, item)
+		}
+	case scalarNode:
+	}
+}
+
+func parseTomlConfig(file string, data []byte) (*configNode, error) {
+	value := make(map[string]any)
+	err := toml.Unmarshal(data, &value)
+	if err != nil {
+		pos := sourcePos{File: file}
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			pos.Line, pos.Column = decodeErr.Position()
+		}
+		return nil, ConfigError{Pos: pos, Message: err.Error()}
+	}
+
+	positions := tomlPositions(file, data)
+	return valueToConfigNode(value, "", sourcePos{File: file, Line: 1, Column: 1}, positions), nil
+}
+
+func valueToConfigNode(value any, path string, pos sourcePos, positions map[string]sourcePos) *configNode {
+	if p, ok := positions[path]; ok {
+		pos = p
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		node := newObjectNode(pos)
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			node.set(key, valueToConfigNode(v[key], joinConfigPath(path, key), pos, positions))
+		}
+		return node
+	case []any:
+		node := &configNode{kind: arrayNode, pos: pos, items: make([]*configNode, len(v))}
+		for i, item := range v {
+			node.items[i] = valueToConfigNode(item, indexConfigPath(path, i), pos, positions)
+		}
+		return node
+	case []map[string]any:
+		node := &configNode{kind: arrayNode, pos: pos, items: make([]*configNode, len(v))}
+		for i, item := range v {
+			node.items[i] = valueToConfigNode(item, indexConfigPath(path, i), pos, positions)
+		}
+		return node
+	default:
+		return &configNode{pos: pos, value: v}
+	}
+}
+
+// tomlPositions collects source positions of tables and keys by their config paths
+func tomlPositions(file string, data []byte) map[string]sourcePos {
+	positions := make(map[string]sourcePos)
+	arrayTables := make(map[string]int)
+	p := unstable.Parser{}
+	p.Reset(data)
+
+	nodePos := func(n *unstable.Node) sourcePos {
+		switch n.Kind {
+		case unstable.KeyValue, unstable.Table, unstable.ArrayTable:
+			keys := n.Key()
+			if keys.Next() {
+				n = keys.Node()
+			}
+		default:
+		}
+		shape := p.Shape(n.Raw)
+		return sourcePos{File: file, Line: shape.Start.Line, Column: shape.Start.Column}
+	}
+	resolve := func(keys unstable.Iterator, defineArray bool) string {
+		path := ""
+		for keys.Next() {
+			path = joinConfigPath(path, string(keys.Node().Data))
+			count, isArray := arrayTables[path]
+			switch {
+			case defineArray && keys.IsLast():
+				arrayTables[path]++
+				path = indexConfigPath(path, count)
+			case isArray:
+				path = indexConfigPath(path, count-1)
+			}
+		}
+		return path
+	}
+
+	prefix := ""
+	for p.NextExpression() {
+		e := p.Expression()
+		switch e.Kind {
+		case unstable.Table:
+			prefix = resolve(e.Key(), false)
+			positions[prefix] = nodePos(e)
+		case unstable.ArrayTable:
+			prefix = resolve(e.Key(), true)
+			positions[prefix] = nodePos(e)
+		case unstable.KeyValue:
+			tomlKeyValuePositions(prefix, e, positions, nodePos)
+		default:
+		}
+	}
+	return positions
+}
+
+func tomlKeyValuePositions(prefix string, kv *unstable.Node, positions map[string]sourcePos, nodePos func(*unstable.Node) sourcePos) {
+	path := prefix
+	keys := kv.Key()
+	for keys.Next() {
+		path = joinConfigPath(path, string(keys.Node().Data))
+	}
+	positions[path] = nodePos(kv)
+	tomlValuePositions(path, kv.Value(), positions, nodePos)
+}
+
+func tomlValuePositions(path string, value *unstable.Node, positions map[string]sourcePos, nodePos func(*unstable.Node) sourcePos) {
+	switch value.Kind {
+	case unstable.InlineTable:
+		children := value.Children()
+		for children.Next() {
+			tomlKeyValuePositions(path, children.Node(), positions, nodePos)
+		}
+	case unstable.Array:
+		children := value.Children()
+		for i := 0; children.Next(); i++ {
+			itemPath := indexConfigPath(path, i)
+			item := children.Node()
+			if item.Raw.Length > 0 {
+				positions[itemPath] = nodePos(item)
+			}
+			tomlValuePositions(itemPath, item, positions, nodePos)
+		}
+	default:
+	}
+}
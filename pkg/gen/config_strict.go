@@ -0,0 +1,193 @@
+package gen
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	maxSuggestionDistance = 3
+)
+
+// checkConfigKeys reports keys, which are unknown for the config structure, and values of unexpected types
+func checkConfigKeys(root *configNode) ConfigErrors {
+	errs := make(ConfigErrors, 0)
+	checkNodeType(root, reflect.TypeOf(Config{}), "", &errs)
+	return errs
+}
+
+// nolint:cyclop
+func checkNodeType(n *configNode, t reflect.Type, path string, errs *ConfigErrors) {
+	if n.kind == scalarNode && n.value == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	expected := ""
+	switch t.Kind() {
+	case reflect.Interface:
+		return
+	case reflect.Struct:
+		if n.kind != objectNode {
+			expected = "object"
+			break
+		}
+		fields := structFieldsByName(t)
+		for _, key := range n.keys {
+			fieldType, ok := fields[key]
+			if !ok {
+				*errs = append(*errs, ConfigError{Pos: n.fields[key].pos, Path: path, Message: unknownKeyMessage(key, fields)})
+				continue
+			}
+			checkNodeType(n.fields[key], fieldType, joinConfigPath(path, key), errs)
+		}
+	case reflect.Map:
+		if n.kind != objectNode {
+			expected = "object"
+			break
+		}
+		for _, key := range n.keys {
+			checkNodeType(n.fields[key], t.Elem(), fmt.Sprintf("%s[%s]", path, key), errs)
+		}
+	case reflect.Slice:
+		if n.kind != arrayNode {
+			expected = "list"
+			break
+		}
+		for i, item := range n.items {
+			checkNodeType(item, t.Elem(), indexConfigPath(path, i), errs)
+		}
+	case reflect.String:
+		if !isStringLike(n) {
+			expected = "string"
+		}
+	case reflect.Bool:
+		if _, ok := n.value.(bool); !ok || n.kind != scalarNode {
+			expected = "boolean"
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !isInteger(n) {
+			expected = "integer"
+		}
+	case reflect.Float32, reflect.Float64:
+		if !isNumber(n) {
+			expected = "number"
+		}
+	default:
+	}
+
+	if expected != "" {
+		*errs = append(*errs, ConfigError{
+			Pos:     n.pos,
+			Path:    path,
+			Message: fmt.Sprintf("expected %s, got %s", expected, n.describe()),
+		})
+	}
+}
+
+func structFieldsByName(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+func unknownKeyMessage(key string, fields map[string]reflect.Type) string {
+	message := fmt.Sprintf("unknown key '%s'", key)
+	suggestion, bestDistance := "", maxSuggestionDistance+1
+	for name := range fields {
+		distance := levenshtein(strings.ToLower(key), strings.ToLower(name))
+		if distance < bestDistance || (distance == bestDistance && name < suggestion) {
+			suggestion, bestDistance = name, distance
+		}
+	}
+	if suggestion != "" && bestDistance <= max(1, len(key)/3) {
+		message += fmt.Sprintf(", did you mean '%s'?", suggestion)
+	}
+	return message
+}
+
+func levenshtein(a string, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(br)]
+}
+
+func isStringLike(n *configNode) bool {
+	if n.kind != scalarNode {
+		return false
+	}
+	switch n.value.(type) {
+	case bool, int64, float64, int, uint64:
+		return false
+	default:
+		return true
+	}
+}
+
+func isNumber(n *configNode) bool {
+	switch n.value.(type) {
+	case int64, float64, int, uint64:
+		return n.kind == scalarNode
+	default:
+		return false
+	}
+}
+
+func isInteger(n *configNode) bool {
+	f, ok := n.value.(float64)
+	if ok {
+		return f == float64(int64(f))
+	}
+	return isNumber(n)
+}
+
+func (n *configNode) describe() string {
+	switch n.kind {
+	case objectNode:
+		return "object"
+	case arrayNode:
+		return "list"
+	default:
+		switch n.value.(type) {
+		case bool:
+			return "boolean"
+		case int64, int, uint64:
+			return "integer"
+		case float64:
+			return "number"
+		default:
+			return fmt.Sprintf("string %q", fmt.Sprint(n.value))
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package gen
 
 import (
 	"bufio"
@@ -7,7 +7,6 @@ import (
 	"math/rand/v2"
 	"os"
 	"slices"
-	"sync"
 
 	"github.com/pkg/errors"
 )
@@ -18,12 +17,10 @@ const (
 
 type csvReader struct {
 	values           []string
-	curIdx           int
 	isReadRandomMode bool
-	locker           sync.Locker
 }
 
-func NewCsvReader(cfg *csvDataSource) (*csvReader, error) {
+func NewCsvReader(cfg *CsvDataSource) (*csvReader, error) {
 	fd, err := os.Open(cfg.Filepath)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "open file '%s'", cfg.Filepath)
@@ -40,31 +37,35 @@ func NewCsvReader(cfg *csvDataSource) (*csvReader, error) {
 	}
 
 	return &csvReader{
-		curIdx:           0,
 		isReadRandomMode: !cfg.DisableReadRandomMode,
 		values:           values,
-		locker:           &sync.Mutex{},
 	}, nil
 }
 
-func (r *csvReader) Read() string {
-	if r.isReadRandomMode {
-		return r.readRandom()
+func readCsvHeader(cfg *CsvDataSource) ([]string, error) {
+	fd, err := os.Open(cfg.Filepath)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "open file '%s'", cfg.Filepath)
 	}
-	return r.readCircular()
-}
+	defer func() { _ = fd.Close() }()
 
-func (r *csvReader) readRandom() string {
-	r.curIdx = rand.IntN(len(r.values)) // nolint:gosec
-	return r.values[r.curIdx]
+	reader := csv.NewReader(fd)
+	if cfg.CsvSeparator != "" {
+		reader.Comma = rune(cfg.CsvSeparator[0])
+	}
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "read column header of '%s'", cfg.Filepath)
+	}
+	return header, nil
 }
 
-func (r *csvReader) readCircular() string {
-	r.locker.Lock()
-	cur := r.curIdx
-	r.curIdx = (cur + 1) % len(r.values)
-	r.locker.Unlock()
-	return r.values[cur]
+// Read returns random value or value at the position, when random mode is disabled, starting over at the end
+func (r *csvReader) Read(rnd *rand.Rand, position int64) string {
+	if r.isReadRandomMode {
+		return r.values[rnd.IntN(len(r.values))]
+	}
+	return r.values[position%int64(len(r.values))]
 }
 
 func loadCsvValuesInMem(reader *csv.Reader, targetField string) ([]string, error) {
@@ -0,0 +1,263 @@
+// Package gen generates records of entities described by Config.
+//
+// Config is loaded from a file by Load or built in code and checked by Validate.
+// Generator writes records of entities into io.Writer sinks or streams them in-process:
+//
+//	cfg, err := gen.Load("config.yml", "")
+//	...
+//	report, err := gen.New(cfg, gen.WithSeed(42)).Write(ctx, map[string]io.Writer{"users": w})
+package gen
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/log"
+)
+
+const (
+	recordsBuffer = 1024
+	// inlineConfigName is a file name in positions of errors of parsed configs
+	inlineConfigName = "config"
+)
+
+var ErrEntityNotFound = errors.New("entity not found")
+
+// Load reads the config with includes and definitions and validates it,
+// format is json, yaml or toml, it is detected by the extension, if it is empty.
+// Errors of the config are ConfigErrors with paths and positions of invalid values
+func Load(path string, format string) (*Config, error) {
+	cfg, err := loadConfig(path, format)
+	if err != nil {
+		return nil, err
+	}
+	err = Validate(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Parse reads the config from data and validates it like Load, but includes and types reading files of the server
+// like ExternalCsvSource are not allowed and env placeholders are not substituted, so it is safe for configs
+// received by network. Format is json, yaml or toml, json is used by default
+func Parse(data []byte, format string) (*Config, error) {
+	root, err := parseConfigTree(inlineConfigName, data, format)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := decodeConfig(root)
+	if err != nil {
+		return nil, err
+	}
+	errs := cfg.fileSourceErrors()
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	err = Validate(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// fileSourceErrors reports types, which read files of the server
+func (cfg *Config) fileSourceErrors() ConfigErrors {
+	var errs ConfigErrors
+	cfg.WalkFields(func(path string, field *Field, _ bool) {
+		t := field.Type
+		if t == nil || (t.Type != ExternalType && t.ExternalCsvSource == nil) {
+			return
+		}
+		typePath := joinConfigPath(path, "Type")
+		errs = append(errs, ConfigError{
+			Pos:     cfg.position(typePath),
+			Path:    typePath,
+			Message: "'external' type and 'ExternalCsvSource' are not allowed, they read files of the server",
+		})
+	})
+	return errs
+}
+
+// Validate checks the config, which is built in code, and creates generators of types by their factories,
+// errors of the config are ConfigErrors
+func Validate(cfg *Config) error {
+	validate := validator.New()
+	validate.RegisterStructValidation(FieldStructLevelValidation, Field{})
+	validate.RegisterStructValidation(ArrayStructLevelValidation, Array{})
+	validate.RegisterStructValidation(ConfigStructLevelValidation, Config{})
+	validate.RegisterStructValidation(RateProfileStructLevelValidation, RateProfile{})
+	var errs ConfigErrors
+	err := validateConfig(validate, cfg)
+	if err != nil && !errors.As(err, &errs) {
+		return err
+	}
+	errs = append(errs, cfg.buildTypes()...)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Record is a generated record of the entity, objects are map[string]any
+type Record struct {
+	Entity    string
+	Iteration int64
+	Value     any
+}
+
+type Option func(opts *GenerateOptions)
+
+// WithSeed makes generation reproducible, a random seed is used by default
+func WithSeed(seed uint64) Option {
+	return func(opts *GenerateOptions) {
+		opts.Seed = seed
+	}
+}
+
+// WithReferenceTime sets the time, which random dates are generated before, the current time is used by default
+func WithReferenceTime(now time.Time) Option {
+	return func(opts *GenerateOptions) {
+		opts.Now = now
+	}
+}
+
+// WithErrorPolicy sets the policy of failed records, FailFastPolicy is used by default
+func WithErrorPolicy(policy ErrorPolicy) Option {
+	return func(opts *GenerateOptions) {
+		opts.Policy = policy
+	}
+}
+
+// WithLogger sets the logger of warnings of fields
+func WithLogger(logger log.Logger) Option {
+	return func(opts *GenerateOptions) {
+		opts.Logger = logger
+	}
+}
+
+// Generator generates records of the validated config,
+// generations of the same config must not run concurrently
+type Generator struct {
+	cfg  *Config
+	opts GenerateOptions
+}
+
+func New(cfg *Config, opts ...Option) *Generator {
+	g := &Generator{
+		cfg: cfg,
+		opts: GenerateOptions{
+			Policy: ErrorPolicy{Name: FailFastPolicy},
+			Seed:   rand.Uint64(), // nolint:gosec
+			Now:    time.Now(),
+		},
+	}
+	for _, opt := range opts {
+		opt(&g.opts)
+	}
+	return g
+}
+
+// Write generates records into sinks by names of entities, records of entities without sinks are discarded.
+// Records are encoded by OutputFormat of entities, csv sinks start with the header
+func (g *Generator) Write(ctx context.Context, sinks map[string]io.Writer) (*GenerationReport, error) {
+	writers := make([]io.Writer, len(g.cfg.Entities))
+	for i := range writers {
+		writers[i] = io.Discard
+	}
+	for name, sink := range sinks {
+		i, err := g.cfg.entityIndex(name)
+		if err != nil {
+			return nil, err
+		}
+		entity := &g.cfg.Entities[i]
+		if entity.Config.OutputFormat == CsvFormat {
+			header, err := CsvHeader(entity)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "csv header of %s", name)
+			}
+			_, err = sink.Write(header)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "write csv header of %s", name)
+			}
+		}
+		writers[i] = sink
+	}
+	return g.cfg.GenerateEntities(ctx, writers, g.opts)
+}
+
+// Stream generates records of all entities in order of iterations until a stop condition of the config is reached
+// or ctx is canceled. Records of started iterations are dropped after cancellation, so reading can be stopped then
+func (g *Generator) Stream(ctx context.Context) *Stream {
+	s := &Stream{
+		records: make(chan Record, recordsBuffer),
+		done:    make(chan struct{}),
+	}
+	opts := g.opts
+	opts.Records = func(record Record) {
+		select {
+		case s.records <- record:
+		case <-ctx.Done():
+		}
+	}
+	writers := make([]io.Writer, len(g.cfg.Entities))
+	for i := range writers {
+		// records are encoded anyway, TargetBytes is measured by encoded records
+		writers[i] = io.Discard
+	}
+	go func() {
+		defer close(s.done)
+		defer close(s.records)
+		s.report, s.err = g.cfg.GenerateEntities(ctx, writers, opts)
+	}()
+	return s
+}
+
+// Stream is a running generation, Records is closed, when it stops
+type Stream struct {
+	records chan Record
+	done    chan struct{}
+	report  *GenerationReport
+	err     error
+}
+
+func (s *Stream) Records() <-chan Record {
+	return s.records
+}
+
+// Wait waits until generation stops and returns its report
+func (s *Stream) Wait() (*GenerationReport, error) {
+	<-s.done
+	return s.report, s.err
+}
+
+// Entity returns the entity by its name
+func (cfg *Config) Entity(name string) (*Entity, error) {
+	i, err := cfg.entityIndex(name)
+	if err != nil {
+		return nil, err
+	}
+	return &cfg.Entities[i], nil
+}
+
+// entityIndex returns the index of the entity by its name
+func (cfg *Config) entityIndex(name string) (int, error) {
+	index := -1
+	for i := range cfg.Entities {
+		if cfg.Entities[i].Name() != name {
+			continue
+		}
+		if index != -1 {
+			return 0, errors.Errorf("entity name '%s' is ambiguous, set Name of entities", name)
+		}
+		index = i
+	}
+	if index == -1 {
+		return 0, errors.WithMessagef(ErrEntityNotFound, "'%s'", name)
+	}
+	return index, nil
+}
@@ -0,0 +1,134 @@
+package gen
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+const (
+	sharedFieldsStream = 0
+	planStream         = 1
+	goldenGamma        = 0x9e3779b97f4a7c15
+)
+
+// GenContext is a state of a single record generation.
+// Random source is seeded by the generation seed, the iteration and the entity,
+// so every record is reproducible regardless of the worker, which generates it.
+// Type generators must take random values only from the context to keep generation reproducible
+type GenContext struct {
+	seed         uint64
+	now          time.Time
+	pcg          *rand.PCG
+	rand         *rand.Rand
+	faker        *gofakeit.Faker
+	alphabets    map[string][]rune
+	sharedFields map[string]any
+	warnings     *warnings
+
+	// record is an index of the generated record, positions of sequences and circular sources are derived from it
+	record int64
+	calls  map[*Type]int64
+}
+
+func newGenContext(seed uint64, now time.Time, alphabets map[string][]rune, warnings *warnings) *GenContext {
+	pcg := rand.NewPCG(seed, seed)
+	return &GenContext{
+		seed:      seed,
+		now:       now,
+		pcg:       pcg,
+		rand:      rand.New(pcg), // nolint:gosec
+		faker:     gofakeit.NewFaker(pcg, false),
+		alphabets: alphabets,
+		warnings:  warnings,
+		calls:     make(map[*Type]int64),
+	}
+}
+
+// reset prepares context to generate record of the stream (shared fields or entity) in the iteration
+func (c *GenContext) reset(stream int, iteration int64, record int64, sharedFields map[string]any) {
+	c.pcg.Seed(mixSeed(c.seed^uint64(stream)*goldenGamma), mixSeed(uint64(iteration))) // nolint:gosec
+	c.record = record
+	c.sharedFields = sharedFields
+	clear(c.calls)
+}
+
+func entityStream(entityIndex int) int {
+	return planStream + 1 + entityIndex
+}
+
+// Rand is the random source of the record
+func (c *GenContext) Rand() *rand.Rand {
+	return c.rand
+}
+
+// Faker generates fake data by the random source of the record
+func (c *GenContext) Faker() *gofakeit.Faker {
+	return c.faker
+}
+
+// Now is the reference time of generation, random dates are generated before it
+func (c *GenContext) Now() time.Time {
+	return c.now
+}
+
+// Position returns unique index of the current call of the type like a number of the sequence,
+// it doesn't depend on order of generated records. Stride of the type is the max number of its calls per record
+func (c *GenContext) Position(t *Type) int64 {
+	call := c.calls[t]
+	c.calls[t] = call + 1
+	return c.record*t.stride + call
+}
+
+// nolint:predeclared
+func (c *GenContext) randRange(min, max int) int {
+	if max == min {
+		return max
+	}
+	val := c.rand.IntN(max-min) + min
+	return val
+}
+
+func (c *GenContext) randPercent() int {
+	return c.rand.IntN(101)
+}
+
+func (c *GenContext) randDate() time.Time {
+	now := c.now.Unix()
+	randOffset := c.rand.Int32() / 2
+
+	date := time.Unix(now-int64(randOffset), 0)
+	return date
+}
+
+// mixSeed is a splitmix64 finalizer, it spreads close values like sequential iterations over the whole range
+func mixSeed(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// setStrides calculates for sequences and circular sources the max number of calls per record
+func setStrides(field *Field, stride int64) {
+	for i := range field.Fields {
+		setStrides(&field.Fields[i], stride)
+	}
+	for i := range field.OneOfFields {
+		setStrides(&field.OneOfFields[i], stride)
+	}
+	if arr := field.Array; arr != nil {
+		if arr.Value != nil {
+			setStrides(arr.Value, stride*int64(max(arr.MinLen, arr.MaxLen, 1)))
+		}
+		for i := range arr.Fixed {
+			setStrides(&arr.Fixed[i], stride)
+		}
+	}
+	if field.Type != nil {
+		field.Type.stride = stride
+	}
+}
@@ -0,0 +1,750 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	json2 "encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/txix-open/isp-kit/log"
+)
+
+const (
+	StringType   = "string"
+	IntType      = "int"
+	FloatType    = "float"
+	DateType     = "date"
+	BoolType     = "bool"
+	UuidType     = "uuid"
+	ConstType    = "const"
+	OneOfType    = "oneof"
+	SequenceType = "sequence"
+	EmailType    = "email"
+	ExternalType = "external"
+	GeoJsonType  = "geo_json"
+)
+
+const (
+	chanBuffer = 100
+)
+
+var emptySharedFields = make(map[string]any)
+
+func (cfg *Config) GenerateSharedFields(gc *GenContext) (map[string]any, error) {
+	sharedFields := make(map[string]any, len(cfg.SharedFields))
+	for _, field := range cfg.SharedFields {
+		if field.Name == "" {
+			return nil, errors.New("invalid shared field: empty name")
+		}
+		val, err := field.Generate(gc)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "shared field '%s'", field.Name)
+		}
+		sharedFields[field.Name] = val
+	}
+
+	return sharedFields, nil
+}
+
+func (cfg *Config) generateAlphabets() map[string][]rune {
+	alphabets := make(map[string][]rune, len(cfg.Alphabets))
+	for _, alphabet := range cfg.Alphabets {
+		alphabets[alphabet.Name] = []rune(alphabet.Values)
+	}
+	return alphabets
+}
+
+// GenerateOptions defines how records are generated
+type GenerateOptions struct {
+	Policy ErrorPolicy
+	// Seed and Now make generation reproducible, Now is a reference time for random dates
+	Seed uint64
+	Now  time.Time
+	// Resume is a state of previous generation to continue, writers must be positioned right after its records
+	Resume *GenerationState
+	// Checkpoint is called every CheckpointInterval and at the end of not aborted generation,
+	// all records of iterations before the state are written and writers are flushed at that moment
+	Checkpoint         func(state GenerationState) error
+	CheckpointInterval time.Duration
+	// ShardIndex and ShardCount split iterations into contiguous ranges, only the range of the shard is generated
+	ShardIndex int
+	ShardCount int
+	// FieldStats enables statistics of fields of written records
+	FieldStats bool
+	// Progress is called every ProgressInterval during generation
+	Progress         func(progress Progress)
+	ProgressInterval time.Duration
+	// Metrics are optional
+	Metrics *Metrics
+	// Logger receives warnings of fields, it is optional
+	Logger log.Logger
+	// Records is called with every record passed to writers in order of iterations, it is optional
+	Records func(record Record)
+}
+
+// GenerationState is enough to continue generation from the iteration with the same seed
+type GenerationState struct {
+	Iteration int64
+	// Elapsed is generation time of all previous runs, it is limited by Duration
+	Elapsed  time.Duration
+	Entities []EntityState
+}
+
+type EntityState struct {
+	// Records is a number of reserved records including failed ones and ones of previous shards,
+	// positions of sequences and circular sources are derived from it
+	Records int64
+	// Written is a number of records passed to the writer of the entity
+	Written int64
+	// Bytes is a size of written records, it is limited by TargetBytes
+	Bytes int64
+	// Elapsed is generation time, when the last record is passed to the writer
+	Elapsed time.Duration
+	Fields  []FieldStats
+}
+
+type iterationTask struct {
+	iteration    int64
+	sharedFields map[string]any
+	// records contains index of entity record or -1, if entity is skipped in the iteration
+	records []int64
+}
+
+type iterationResult struct {
+	*iterationTask
+	bufs []*bytes.Buffer
+	// values are generated records, they are kept for statistics
+	values []any
+}
+
+// GenerateEntities generates records of all entities into writers, returned error is a reason of aborted generation.
+// When ctx is canceled no more iterations are started, already started ones are generated and written.
+// Records are written in order of iterations, so the output depends only on the seed
+func (cfg *Config) GenerateEntities(ctx context.Context, writers []io.Writer, opts GenerateOptions) (*GenerationReport, error) {
+	// generators are created again, the config could be changed after validation
+	typeErrs := cfg.buildTypes()
+	if len(typeErrs) > 0 {
+		return &GenerationReport{Seed: opts.Seed}, typeErrs
+	}
+
+	workersCount := runtime.NumCPU() * 2
+	errs := newErrorCollector(opts.Policy)
+	if opts.Metrics != nil {
+		errs.errorsMetric = opts.Metrics.errors
+	}
+	alphabets := cfg.generateAlphabets()
+	cfg.WalkFields(func(path string, field *Field, _ bool) {
+		field.path = path
+	})
+	warnings := newWarnings(opts.Logger)
+	defer warnings.flush()
+	gc := newGenContext(opts.Seed, opts.Now, alphabets, warnings)
+	first, last := cfg.ShardRange(opts.ShardIndex, opts.ShardCount)
+	state := cfg.prepareGeneration(gc, first, opts.Resume)
+
+	tasksCh := make(chan *iterationTask, chanBuffer)
+	resultsCh := make(chan *iterationResult, chanBuffer)
+	workersWg := new(sync.WaitGroup)
+	writersWg := new(sync.WaitGroup)
+
+	counters := newProgressCounters(state, first)
+	writersChs := make([]chan writeRequest, len(writers))
+	stats := make([]*entityStats, len(writers))
+	entityMetrics := make([]*entityMetrics, len(writers))
+	writersWg.Add(len(writers))
+	for i := range writers {
+		ch := make(chan writeRequest, chanBuffer)
+		writersChs[i] = ch
+		if opts.FieldStats {
+			stats[i] = newEntityStats(&cfg.Entities[i], state.Entities[i].Fields)
+		}
+		entityMetrics[i] = opts.Metrics.entity(cfg.Entities[i].Config.Filepath)
+
+		// rate limited records are flushed one by one to be delivered steadily
+		flushEach := cfg.RatePerSecond > 0 || cfg.Entities[i].Config.RatePerSecond > 0
+		go newWriterWorker(ch, writersWg, writers[i], flushEach, stats[i], &counters.entities[i], entityMetrics[i], errs)
+	}
+
+	keepValues := opts.FieldStats || opts.Records != nil
+	workersWg.Add(workersCount)
+	for range workersCount {
+		gc := newGenContext(opts.Seed, opts.Now, alphabets, warnings)
+		go newWorker(tasksCh, resultsCh, workersWg, cfg.Entities, gc, keepValues, &counters.iterations,
+			entityMetrics, errs)
+	}
+
+	targets := make([]int64, len(cfg.Entities))
+	for i := range cfg.Entities {
+		targets[i] = cfg.Entities[i].Config.shardTargetBytes(opts.ShardCount)
+	}
+	names := make([]string, len(cfg.Entities))
+	for i := range cfg.Entities {
+		names[i] = cfg.Entities[i].Name()
+	}
+	seq := newSequencer(state, writersChs, stats, names, workersCount*chanBuffer, targets, opts, errs)
+	sequencerDone := make(chan struct{})
+	go func() {
+		defer close(sequencerDone)
+		seq.run(resultsCh)
+	}()
+
+	var deadline <-chan time.Time
+	if duration := cfg.duration(); duration > 0 {
+		timer := time.NewTimer(duration - state.Elapsed)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	if opts.Metrics != nil {
+		stopSampling := make(chan struct{})
+		defer close(stopSampling)
+		go opts.Metrics.sampleChannels(stopSampling, tasksCh, resultsCh, writersChs, cfg.Entities)
+	}
+
+	if opts.Progress != nil && opts.ProgressInterval > 0 {
+		total := int64(0)
+		if cfg.TotalCount > 0 {
+			total = last - first
+		}
+		reporter := newProgressReporter(cfg, counters, total, targets, opts, state.Elapsed)
+		stopProgress := make(chan struct{})
+		progressDone := make(chan struct{})
+		go func() {
+			defer close(progressDone)
+			reporter.run(stopProgress)
+		}()
+		// progress is not reported after the generation result
+		defer func() {
+			close(stopProgress)
+			<-progressDone
+		}()
+	}
+
+	limiter := newRateLimiter(shardRate(cfg.RatePerSecond, opts.ShardCount), cfg.RateBurst, cfg.RateProfile, ctx.Done())
+	entityLimiters := make([]*rateLimiter, len(cfg.Entities))
+	for i := range cfg.Entities {
+		conf := cfg.Entities[i].Config
+		entityLimiters[i] = newRateLimiter(shardRate(conf.RatePerSecond, opts.ShardCount), conf.RateBurst, conf.RateProfile, ctx.Done())
+	}
+
+	report := &GenerationReport{Seed: opts.Seed}
+generation:
+	for iteration := state.Iteration; iteration < last; iteration++ {
+		limiter.wait()
+		select {
+		case seq.window <- struct{}{}:
+		case <-errs.done():
+			break generation
+		case <-seq.stopped:
+			break generation
+		case <-deadline:
+			report.Stopped = fmt.Sprintf("duration %s reached", cfg.Duration)
+			break generation
+		case <-ctx.Done():
+			report.Interrupted = true
+			break generation
+		}
+		task := cfg.planIteration(gc, iteration, errs)
+		// records are paced before generation, so no generated records are left to write slowly after stop.
+		// Iterations are shared by entities, so the slowest entity sets the pace of all of them
+		for i, record := range task.records {
+			if record >= 0 {
+				entityLimiters[i].wait()
+			}
+		}
+		tasksCh <- task
+	}
+
+	close(tasksCh)
+	workersWg.Wait()
+	close(resultsCh)
+	<-sequencerDone
+	for i := range writersChs {
+		close(writersChs[i])
+	}
+	writersWg.Wait()
+
+	report.Iterations = seq.state.Iteration - first
+	report.Elapsed = seq.elapsed()
+	if i := seq.reachedTarget; i != -1 {
+		report.Stopped = fmt.Sprintf("target %d bytes of %s reached", targets[i], cfg.Entities[i].Config.Filepath)
+	}
+	for i := range cfg.Entities {
+		entity := EntityReport{
+			Filepath: cfg.Entities[i].Config.Filepath,
+			Records:  seq.state.Entities[i].Written,
+			Bytes:    seq.state.Entities[i].Bytes,
+			Elapsed:  seq.state.Entities[i].Elapsed,
+		}
+		if stats[i] != nil {
+			entity.Fields = stats[i].snapshot()
+		}
+		report.Entities = append(report.Entities, entity)
+	}
+	errs.fill(report)
+	return report, errs.err()
+}
+
+// ShardRange returns the first and the next after the last iterations of the shard,
+// generation without TotalCount is limited only by other stop conditions
+func (cfg *Config) ShardRange(index int, count int) (int64, int64) {
+	total := int64(cfg.TotalCount)
+	if total == 0 {
+		return 0, math.MaxInt64
+	}
+	if count <= 1 {
+		return 0, total
+	}
+	return total * int64(index) / int64(count), total * int64(index+1) / int64(count)
+}
+
+// prepareGeneration restores counters of entities from the state or reserves records of iterations before the first one
+// and returns the state to start generation from
+func (cfg *Config) prepareGeneration(gc *GenContext, first int64, resume *GenerationState) GenerationState {
+	for i := range cfg.SharedFields {
+		setStrides(&cfg.SharedFields[i], 1)
+	}
+	for i := range cfg.Entities {
+		setStrides(&cfg.Entities[i].Field, 1)
+		// counts of the previous generation of the config are dropped
+		cfg.Entities[i].Config.currentCount = 0
+		// csv columns are cached before workers, which read them concurrently
+		cfg.Entities[i].CsvColumns()
+	}
+
+	state := GenerationState{Entities: make([]EntityState, len(cfg.Entities))}
+	if resume == nil {
+		cfg.skipIterations(gc, 0, first)
+		state.Iteration = first
+		for i := range cfg.Entities {
+			state.Entities[i].Records = cfg.Entities[i].Config.currentCount
+		}
+		return state
+	}
+
+	state.Iteration = resume.Iteration
+	copy(state.Entities, resume.Entities)
+	for i := range cfg.Entities {
+		cfg.Entities[i].Config.currentCount = state.Entities[i].Records
+	}
+	return state
+}
+
+// skipIterations reserves records of entities in iterations, which are generated by previous shards,
+// so indexes of records and Count limits are the same as in a single generation
+func (cfg *Config) skipIterations(gc *GenContext, from int64, to int64) {
+	rated := slices.ContainsFunc(cfg.Entities, func(e Entity) bool {
+		return e.Config.Rate > 0
+	})
+	if !rated {
+		// reservation doesn't depend on random values, so it can be calculated at once
+		for i := range cfg.Entities {
+			conf := &cfg.Entities[i].Config
+			conf.currentCount += to - from
+			if conf.Count > 0 {
+				conf.currentCount = min(conf.currentCount, conf.Count)
+			}
+		}
+		return
+	}
+
+	for iteration := from; iteration < to; iteration++ {
+		gc.reset(planStream, iteration, iteration, emptySharedFields)
+		for i := range cfg.Entities {
+			cfg.Entities[i].reserveRecord(gc)
+		}
+	}
+}
+
+// planIteration reserves records of entities and generates shared fields, it is called sequentially for every iteration
+func (cfg *Config) planIteration(gc *GenContext, iteration int64, errs *errorCollector) *iterationTask {
+	task := &iterationTask{
+		iteration: iteration,
+		records:   make([]int64, len(cfg.Entities)),
+	}
+
+	// reservation uses own random stream, so it doesn't depend on generated shared fields
+	gc.reset(planStream, iteration, iteration, emptySharedFields)
+	for i := range cfg.Entities {
+		task.records[i] = cfg.Entities[i].reserveRecord(gc)
+	}
+
+	gc.reset(sharedFieldsStream, iteration, iteration, emptySharedFields)
+	sharedFields, err := cfg.GenerateSharedFields(gc)
+	if err != nil {
+		// reserved records are counted as failed, entities are not generated without shared fields
+		errs.add(GenerateErrorKind, err)
+		return task
+	}
+	task.sharedFields = sharedFields
+	return task
+}
+
+func newWorker(tasksCh <-chan *iterationTask, resultsCh chan<- *iterationResult, wg *sync.WaitGroup,
+	entities []Entity, gc *GenContext, keepValues bool, iterations *atomic.Int64, metrics []*entityMetrics,
+	errs *errorCollector) {
+	defer wg.Done()
+
+	for task := range tasksCh {
+		result := &iterationResult{
+			iterationTask: task,
+			bufs:          make([]*bytes.Buffer, len(entities)),
+		}
+		if keepValues {
+			result.values = make([]any, len(entities))
+		}
+		// result of aborted generation is still passed to keep order of iterations
+		skipped := errs.isAborted()
+		for i := range entities {
+			if skipped || task.records[i] < 0 || task.sharedFields == nil {
+				continue
+			}
+			entity := &entities[i]
+			startedAt := time.Now()
+			gc.reset(entityStream(i), task.iteration, task.records[i], task.sharedFields)
+			val, err := entity.Field.Generate(gc)
+			if err != nil {
+				errs.add(GenerateErrorKind, errors.WithMessagef(err, "entity '%s'", entity.Config.Filepath))
+				continue
+			}
+
+			var buf *bytes.Buffer
+			switch entity.Config.OutputFormat {
+			case CsvFormat:
+				buf, err = writeCsv(val, entity)
+			default:
+				buf, err = writeJson(val)
+			}
+			if err != nil {
+				errs.add(EncodeErrorKind, errors.WithMessagef(err, "entity '%s'", entity.Config.Filepath))
+				continue
+			}
+			metrics[i].generated(startedAt)
+			result.bufs[i] = buf
+			if keepValues {
+				result.values[i] = val
+			}
+		}
+		resultsCh <- result
+		iterations.Add(1)
+	}
+}
+
+// reserveRecord returns index of the entity record in the iteration or -1, if entity is skipped due to Count and Rate
+func (ent *Entity) reserveRecord(gc *GenContext) int64 {
+	cfg := &ent.Config
+	switch {
+	case cfg.Count > 0 && cfg.currentCount < cfg.Count:
+	case cfg.Rate > 0 && gc.randPercent() <= cfg.Rate,
+		cfg.Count == 0 && cfg.Rate == 0:
+	default:
+		return -1
+	}
+	cfg.currentCount++
+	return cfg.currentCount - 1
+}
+
+// exhausted reports that the entity has no more records, it is limited by Count only and the Count is reached
+func (ent *Entity) exhausted() bool {
+	cfg := &ent.Config
+	return cfg.Count > 0 && cfg.Rate == 0 && cfg.currentCount >= cfg.Count
+}
+
+func (ent *Entity) CsvColumns() []string {
+	switch {
+	case ent.csvColumnsCache != nil:
+		return ent.csvColumnsCache
+	case len(ent.Field.Fields) > 0:
+		ent.csvColumnsCache = makeCsvColumnsFromFields(ent.Field.Fields)
+	case len(ent.Field.OneOfFields) > 0:
+		ent.csvColumnsCache = makeCsvColumnsFromOneOfFields(ent.Field.OneOfFields)
+	}
+	return ent.csvColumnsCache
+}
+
+// nolint:cyclop
+func (f *Field) Generate(gc *GenContext) (any, error) {
+	if f.NilChance > 0 && gc.randPercent() <= f.NilChance {
+		return nil, nil
+	}
+
+	if fields := f.Fields; fields != nil {
+		m := make(map[string]any, len(fields))
+		for _, f := range fields {
+			val, err := f.Generate(gc)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "field '%s'", f.Name)
+			}
+			m[f.Name] = val
+		}
+		return m, nil
+	}
+
+	//nolint:nestif
+	if arr := f.Array; arr != nil {
+		if arr.Fixed != nil {
+			result := make([]any, 0, len(arr.Fixed))
+			for i := range arr.Fixed {
+				field := arr.Fixed[i]
+				val, err := field.Generate(gc)
+				if err != nil {
+					return nil, errors.WithMessagef(err, "fixed item %d", i)
+				}
+				if val == nil {
+					continue
+				}
+				result = append(result, val)
+			}
+			return result, nil
+		}
+
+		size := gc.randRange(arr.MinLen, arr.MaxLen)
+		if size == 0 && arr.MaxLen == 0 {
+			gc.warnings.warn(f.path, "zero max array length, probably mistake")
+		}
+		result := make([]any, 0, size)
+		for range size {
+			val, err := arr.Value.Generate(gc)
+			if err != nil {
+				return nil, errors.WithMessage(err, "array item")
+			}
+			if val == nil {
+				continue
+			}
+			result = append(result, val)
+		}
+		return result, nil
+	}
+
+	if len(f.OneOfFields) > 0 {
+		val, err := generateRandomOneOfField(gc, f.OneOfFields)
+		if err != nil {
+			return nil, errors.WithMessage(err, "generate random one of field")
+		}
+		return val, nil
+	}
+
+	if f.Type != nil {
+		return f.Type.GenerateByType(gc)
+	}
+
+	return nil, errors.New("invalid field: zero path at generating")
+}
+
+// nolint:nonamedreturns
+func (t *Type) GenerateByType(gc *GenContext) (val any, err error) {
+	switch {
+	case t.Reference != "":
+		var ok bool
+		val, ok = gc.sharedFields[t.Reference]
+		if !ok {
+			return nil, errors.Errorf("reference %s not found", t.Reference)
+		}
+	case t.generator == nil:
+		return nil, errors.Errorf("generator of type '%s' is not created, validate the config", t.Type)
+	default:
+		val, err = t.generator.Generate(gc)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "generate by type: %s", t.Type)
+		}
+	}
+
+	if t.AsString {
+		val = fmt.Sprintf("%v", val)
+	}
+	if t.Template != "" {
+		val = fmt.Sprintf(t.Template, val)
+	}
+	if t.AsJson {
+		v, _ := val.(string)
+		val = json2.RawMessage(v)
+	}
+
+	return val, err
+}
+
+func (t *Type) generateByAlphabet(gc *GenContext) (any, error) {
+	alphabet, ok := gc.alphabets[t.Alphabet]
+	if !ok {
+		return nil, errors.Errorf("not found alphabet '%s'", t.Alphabet)
+	}
+
+	minLength, maxLength, err := t.getMinMaxIntegers()
+	if err != nil {
+		return nil, errors.WithMessage(err, "get min max integers")
+	}
+	length := gc.randRange(minLength, maxLength)
+	if length == 0 {
+		length = int(gc.faker.Uint8()) + 1
+	}
+
+	var b strings.Builder
+	b.Grow(length)
+	for range length {
+		i := gc.rand.IntN(len(alphabet))
+		b.WriteRune(alphabet[i])
+	}
+
+	return b.String(), nil
+}
+
+func (t *Type) generateString(gc *GenContext) (any, error) {
+	if t.Alphabet != "" {
+		return t.generateByAlphabet(gc)
+	}
+
+	mn, mx, err := t.getMinMaxIntegers()
+	if err != nil {
+		return nil, errors.WithMessage(err, "get min max integers")
+	}
+	length := gc.randRange(mn, mx)
+	if length != 0 {
+		// TODO: adjust the length of the generated string, otherwise big string is generated every time
+		str := gc.faker.HipsterSentence(4)
+		if len(str) > length {
+			return str[:length], nil
+		}
+		return str, nil
+	}
+
+	return gc.faker.Word(), nil
+}
+
+func (t *Type) generateDate(gc *GenContext) (any, error) {
+	var result time.Time
+	if t.Min != nil && t.Max != nil {
+		minDate, maxDate, err := t.getMinMaxDates()
+		if err != nil {
+			return nil, errors.WithMessage(err, "get min max dates")
+		}
+		result = gc.faker.DateRange(minDate, maxDate)
+	} else {
+		result = gc.randDate()
+	}
+
+	if t.DateFormat != "" {
+		return result.Format(t.DateFormat), nil
+	}
+
+	return result, nil
+}
+
+// generateSequence returns value by position of the call, so it doesn't depend on order of generated records
+// nolint:predeclared
+func (t *Type) generateSequence(gc *GenContext) (any, error) {
+	min, max, err := t.getMinMaxIntegers()
+	if err != nil {
+		return nil, errors.WithMessage(err, "get min max integers")
+	}
+	v := int64(min) + gc.Position(t)
+	if max > 0 && v > int64(max) {
+		return max, nil
+	}
+	return v, nil
+}
+
+// nolint:nonamedreturns,predeclared
+func (t *Type) getMinMaxIntegers() (min int, max int, err error) {
+	if t.Min == nil || t.Max == nil {
+		return 0, 0, nil
+	}
+
+	mn, ok := t.Min.(float64)
+	if !ok {
+		return 0, 0, errors.Errorf("expect min as float64; got %T", t.Min)
+	}
+	mx, ok := t.Max.(float64)
+	if !ok {
+		return 0, 0, errors.Errorf("expect max as float64; got %T", t.Min)
+	}
+
+	return int(mn), int(mx), nil
+}
+
+// nolint:nonamedreturns,predeclared
+func (t *Type) getMinMaxDates() (min time.Time, max time.Time, err error) {
+	mn, ok := t.Min.(string)
+	if !ok {
+		return time.Time{}, time.Time{}, errors.Errorf("expect min as string; got %T", t.Min)
+	}
+	min, err = time.Parse(time.DateOnly, mn)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.WithMessage(err, "parse min date string")
+	}
+
+	mx, ok := t.Max.(string)
+	if !ok {
+		return time.Time{}, time.Time{}, errors.Errorf("expect max as string; got %T", t.Min)
+	}
+	max, err = time.Parse(time.DateOnly, mx)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.WithMessage(err, "parse max date string")
+	}
+
+	return min, max, nil
+}
+
+func generateRandomOneOfField(gc *GenContext, oneOf []Field) (any, error) {
+	if oneOf[0].Weight > 0 {
+		return generateRandomWeightedOneOfField(gc, oneOf)
+	}
+	i := gc.rand.IntN(len(oneOf))
+	return oneOf[i].Generate(gc)
+}
+
+// generateRandomWeightedOneOfField picks the field with probability of its weight, weights are normalized by their sum,
+// because validation allows sums slightly different from 1
+func generateRandomWeightedOneOfField(gc *GenContext, oneOf []Field) (any, error) {
+	var total float64
+	for _, v := range oneOf {
+		if v.Weight == 0 {
+			return nil, errors.Errorf("expect non-zero weight")
+		}
+		total += v.Weight
+	}
+
+	var (
+		r   = gc.rand.Float64() * total
+		sum float64
+	)
+	for _, v := range oneOf[:len(oneOf)-1] {
+		sum += v.Weight
+		if sum > r {
+			return v.Generate(gc)
+		}
+	}
+	// the last field takes the rest of the range, so rounding of the sum never leaves r unmatched
+	return oneOf[len(oneOf)-1].Generate(gc)
+}
+
+func makeCsvColumnsFromFields(fields []Field) []string {
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.Name
+	}
+	return columns
+}
+
+func makeCsvColumnsFromOneOfFields(oneOfFields []Field) []string {
+	dict := make(map[string]bool)
+	for _, field := range oneOfFields {
+		for _, v := range field.Fields {
+			dict[v.Name] = true
+		}
+	}
+	columns := make([]string, 0, len(oneOfFields))
+	for field := range dict {
+		columns = append(columns, field)
+	}
+	slices.Sort(columns)
+	return columns
+}
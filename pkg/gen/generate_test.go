@@ -0,0 +1,131 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+const (
+	testSeed       = 42
+	testTotalCount = 500
+)
+
+var testReferenceTime = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// newDeterminismConfig covers values, which depend on positions of records: sequences in plain, nilable and array fields,
+// weighted branches and entities skipped by Count and Rate
+func newDeterminismConfig(t *testing.T) *Config {
+	t.Helper()
+
+	cfg := &Config{
+		TotalCount: testTotalCount,
+		SharedFields: []Field{
+			{Name: "tenant", Type: &Type{Type: UuidType}},
+		},
+		Entities: []Entity{{
+			Field: Field{Fields: []Field{
+				{Name: "id", Type: &Type{Type: SequenceType, Min: 1.0, Max: 0.0}},
+				{Name: "tenant", Type: &Type{Reference: "tenant"}},
+				{Name: "code", NilChance: 30, Type: &Type{Type: SequenceType}},
+				{Name: "tags", Array: &Array{MinLen: 0, MaxLen: 4, Value: &Field{Type: &Type{Type: SequenceType}}}},
+				{Name: "kind", OneOfFields: []Field{
+					{Weight: 0.3, Type: &Type{Type: ConstType, Const: "a"}},
+					{Weight: 0.7, Type: &Type{Type: IntType, Min: 1.0, Max: 100.0}},
+				}},
+			}},
+			Config: EntityConfig{Name: "users", Filepath: StdoutTarget},
+		}, {
+			Field:  Field{Fields: []Field{{Name: "id", Type: &Type{Type: SequenceType}}, {Name: "email", Type: &Type{Type: EmailType}}}},
+			Config: EntityConfig{Name: "orders", Filepath: StdoutTarget, Rate: 40, OutputFormat: CsvFormat},
+		}, {
+			Field:  Field{Fields: []Field{{Name: "date", Type: &Type{Type: DateType}}}},
+			Config: EntityConfig{Name: "events", Filepath: StdoutTarget, Count: 123},
+		}},
+	}
+	err := Validate(cfg)
+	if err != nil {
+		t.Fatalf("validate config: %v", err)
+	}
+	return cfg
+}
+
+func generateOutputs(t *testing.T, ctx context.Context, opts GenerateOptions) ([]*bytes.Buffer, GenerationState) {
+	t.Helper()
+
+	cfg := newDeterminismConfig(t)
+	outputs := make([]*bytes.Buffer, len(cfg.Entities))
+	writers := make([]io.Writer, len(cfg.Entities))
+	for i := range outputs {
+		outputs[i] = new(bytes.Buffer)
+		writers[i] = outputs[i]
+	}
+	var state GenerationState
+	opts.Policy = ErrorPolicy{Name: FailFastPolicy}
+	opts.Seed = testSeed
+	opts.Now = testReferenceTime
+	opts.Checkpoint = func(s GenerationState) error {
+		state = s
+		return nil
+	}
+	opts.CheckpointInterval = time.Hour
+	_, err := cfg.GenerateEntities(ctx, writers, opts)
+	if err != nil {
+		t.Fatalf("generate entities: %v", err)
+	}
+	return outputs, state
+}
+
+func TestShardedGenerationEqualsSingleRun(t *testing.T) {
+	expected, _ := generateOutputs(t, context.Background(), GenerateOptions{})
+
+	for _, shardCount := range []int{2, 3, 7} {
+		joined := make([][]byte, len(expected))
+		for shard := range shardCount {
+			outputs, _ := generateOutputs(t, context.Background(), GenerateOptions{ShardIndex: shard, ShardCount: shardCount})
+			for i := range outputs {
+				joined[i] = append(joined[i], outputs[i].Bytes()...)
+			}
+		}
+		for i := range expected {
+			if !bytes.Equal(expected[i].Bytes(), joined[i]) {
+				t.Errorf("%d shards: output of entity %d differs from the single run", shardCount, i)
+			}
+		}
+	}
+}
+
+func TestResumedGenerationEqualsSingleRun(t *testing.T) {
+	expected, _ := generateOutputs(t, context.Background(), GenerateOptions{})
+
+	for _, stopAfter := range []int{1, 97, 300} {
+		ctx, cancel := context.WithCancel(context.Background())
+		records := 0
+		first, state := generateOutputs(t, ctx, GenerateOptions{
+			Records: func(Record) {
+				records++
+				if records == stopAfter {
+					cancel()
+				}
+			},
+		})
+		cancel()
+		if state.Iteration == 0 || state.Iteration >= testTotalCount {
+			t.Fatalf("stop after %d records: expected interrupted generation, got iteration %d", stopAfter, state.Iteration)
+		}
+
+		second, _ := generateOutputs(t, context.Background(), GenerateOptions{Resume: &state})
+		for i := range expected {
+			if int64(first[i].Len()) != state.Entities[i].Bytes {
+				t.Fatalf("stop after %d records: entity %d has %d bytes, checkpoint has %d",
+					stopAfter, i, first[i].Len(), state.Entities[i].Bytes)
+			}
+			resumed := append(first[i].Bytes(), second[i].Bytes()...)
+			if !bytes.Equal(expected[i].Bytes(), resumed) {
+				t.Errorf("stop after %d records: output of entity %d differs from the single run", stopAfter, i)
+			}
+		}
+	}
+}
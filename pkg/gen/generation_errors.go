@@ -0,0 +1,162 @@
+package gen
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	FailFastPolicy   = "fail-fast"
+	SkipRecordPolicy = "skip-record"
+	MaxErrorsPolicy  = "max-errors"
+
+	GenerateErrorKind = "generate"
+	EncodeErrorKind   = "encode"
+	WriteErrorKind    = "write"
+
+	maxErrorSamples = 10
+)
+
+// ErrorPolicy defines what to do with records, which failed to generate, encode or write
+type ErrorPolicy struct {
+	Name      string
+	MaxErrors int64
+}
+
+func ParseErrorPolicy(value string) (ErrorPolicy, error) {
+	name, limit, hasLimit := strings.Cut(value, "=")
+	switch {
+	case name == FailFastPolicy && !hasLimit, name == SkipRecordPolicy && !hasLimit:
+		return ErrorPolicy{Name: name}, nil
+	case name == MaxErrorsPolicy && hasLimit:
+		maxErrors, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || maxErrors < 0 {
+			return ErrorPolicy{}, errors.Errorf("invalid errors limit %q", limit)
+		}
+		return ErrorPolicy{Name: name, MaxErrors: maxErrors}, nil
+	default:
+		return ErrorPolicy{}, errors.Errorf("unknown error policy %q, expected %s, %s or %s=N",
+			value, FailFastPolicy, SkipRecordPolicy, MaxErrorsPolicy)
+	}
+}
+
+func (p ErrorPolicy) String() string {
+	if p.Name == MaxErrorsPolicy {
+		return p.Name + "=" + strconv.FormatInt(p.MaxErrors, 10)
+	}
+	return p.Name
+}
+
+// errorCollector counts errors from generation workers and aborts generation according to the policy,
+// write errors always abort generation
+type errorCollector struct {
+	policy ErrorPolicy
+
+	lock     sync.Mutex
+	total    int64
+	byKind   map[string]int64
+	samples  []string
+	abortErr error
+	aborted  chan struct{}
+	// errorsMetric is optional
+	errorsMetric *prometheus.CounterVec
+}
+
+func newErrorCollector(policy ErrorPolicy) *errorCollector {
+	return &errorCollector{
+		policy:  policy,
+		byKind:  make(map[string]int64),
+		aborted: make(chan struct{}),
+	}
+}
+
+func (c *errorCollector) add(kind string, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.total++
+	c.byKind[kind]++
+	if c.errorsMetric != nil {
+		c.errorsMetric.WithLabelValues(kind).Inc()
+	}
+	if len(c.samples) < maxErrorSamples {
+		c.samples = append(c.samples, kind+": "+err.Error())
+	}
+
+	switch {
+	case kind == WriteErrorKind, c.policy.Name == FailFastPolicy:
+		c.abort(err)
+	case c.policy.Name == MaxErrorsPolicy && c.total > c.policy.MaxErrors:
+		c.abort(errors.WithMessagef(err, "errors limit %d exceeded, last error", c.policy.MaxErrors))
+	}
+}
+
+// abort must be called with acquired lock
+func (c *errorCollector) abort(err error) {
+	if c.abortErr != nil {
+		return
+	}
+	c.abortErr = err
+	close(c.aborted)
+}
+
+func (c *errorCollector) done() <-chan struct{} {
+	return c.aborted
+}
+
+func (c *errorCollector) isAborted() bool {
+	select {
+	case <-c.aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *errorCollector) err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.abortErr
+}
+
+func (c *errorCollector) fill(report *GenerationReport) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	report.Errors = c.total
+	report.ErrorsByKind = make(map[string]int64, len(c.byKind))
+	for kind, count := range c.byKind {
+		report.ErrorsByKind[kind] = count
+	}
+	report.ErrorSamples = append(report.ErrorSamples, c.samples...)
+}
+
+// GenerationReport summarizes finished generation
+type GenerationReport struct {
+	Seed       uint64
+	Iterations int64
+	// Elapsed is generation time including previous runs before resume
+	Elapsed     time.Duration
+	Interrupted bool
+	// Stopped is a reached stop condition other than TotalCount
+	Stopped      string
+	Entities     []EntityReport
+	Errors       int64
+	ErrorsByKind map[string]int64
+	ErrorSamples []string
+}
+
+type EntityReport struct {
+	Filepath string
+	Records  int64
+	Bytes    int64
+	// Elapsed is generation time of the last record including previous runs before resume
+	Elapsed time.Duration
+	// Fields are set, if GenerateOptions.FieldStats is enabled
+	Fields []FieldStats
+}
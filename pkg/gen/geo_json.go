@@ -1,14 +1,17 @@
 // nolint:tagliatelle
-package main
+package gen
 
 import (
-	"math/rand"
+	"fmt"
+	"slices"
 	"sort"
+	"strings"
 
-	"github.com/brianvoe/gofakeit/v7"
 	"github.com/pkg/errors"
 )
 
+var supportedGeometries = []string{"Point", "MultiPoint", "LineString", "MultiLineString", "Polygon", "MultiPolygon"}
+
 type GeoJson struct {
 	FeatureType      string            `json:",omitempty"` // GeoJSON feature type, usually "Feature" by default
 	GeoSrs           string            `json:",omitempty"` // Spatial Reference System identifier, e.g. "EPSG:4326"
@@ -31,14 +34,14 @@ type GeoGeometrySpec struct {
 }
 
 // nolint:cyclop,funlen
-func (spec *GeoGeometrySpec) GenerateCoordinates() any {
+func (spec *GeoGeometrySpec) GenerateCoordinates(gc *GenContext) any {
 	if spec.Coordinates != nil {
 		return spec.Coordinates
 	}
 
 	count := spec.MinPoints
 	if spec.MaxPoints > spec.MinPoints {
-		count = spec.MinPoints + rand.Intn(spec.MaxPoints-spec.MinPoints+1)
+		count = spec.MinPoints + gc.rand.IntN(spec.MaxPoints-spec.MinPoints+1)
 	}
 	if count == 0 {
 		count = 1
@@ -54,8 +57,8 @@ func (spec *GeoGeometrySpec) GenerateCoordinates() any {
 	}
 
 	generatePoint := func() []float64 {
-		lon := longitudeInRange(minLon, maxLon)
-		lat := latitudeInRange(minLat, maxLat)
+		lon := longitudeInRange(gc, minLon, maxLon)
+		lat := latitudeInRange(gc, minLat, maxLat)
 		return []float64{lon, lat}
 	}
 
@@ -82,7 +85,7 @@ func (spec *GeoGeometrySpec) GenerateCoordinates() any {
 		for i := range count {
 			nPoints := spec.MinPoints
 			if spec.MaxPoints > spec.MinPoints {
-				nPoints = spec.MinPoints + rand.Intn(spec.MaxPoints-spec.MinPoints+1)
+				nPoints = spec.MinPoints + gc.rand.IntN(spec.MaxPoints-spec.MinPoints+1)
 			}
 			line := make([][]float64, nPoints)
 			for j := range nPoints {
@@ -93,7 +96,7 @@ func (spec *GeoGeometrySpec) GenerateCoordinates() any {
 		return multiLine
 
 	case "Polygon":
-		points := randomConvexPolygon(count, minLon, maxLon, minLat, maxLat)
+		points := randomConvexPolygon(gc, count, minLon, maxLon, minLat, maxLat)
 		points = append(points, points[0])
 		return [][][]float64{points}
 
@@ -105,7 +108,7 @@ func (spec *GeoGeometrySpec) GenerateCoordinates() any {
 
 		multiPolygons := make([][][][]float64, nPolygons)
 		for i := range nPolygons {
-			polygon := randomConvexPolygon(count, minLon, maxLon, minLat, maxLat)
+			polygon := randomConvexPolygon(gc, count, minLon, maxLon, minLat, maxLat)
 			polygon = append(polygon, polygon[0])
 			multiPolygons[i] = [][][]float64{polygon}
 		}
@@ -115,12 +118,12 @@ func (spec *GeoGeometrySpec) GenerateCoordinates() any {
 	}
 }
 
-func randomConvexPolygon(n int, minLon, maxLon, minLat, maxLat float64) [][]float64 {
+func randomConvexPolygon(gc *GenContext, n int, minLon, maxLon, minLat, maxLat float64) [][]float64 {
 	points := make([][]float64, n)
 	for i := range n {
 		points[i] = []float64{
-			longitudeInRange(minLon, maxLon),
-			latitudeInRange(minLat, maxLat),
+			longitudeInRange(gc, minLon, maxLon),
+			latitudeInRange(gc, minLat, maxLat),
 		}
 	}
 
@@ -165,14 +168,41 @@ func convexHull(points [][]float64) [][]float64 {
 	return append(lower[:len(lower)-1], upper[:len(upper)-1]...)
 }
 
-func (t *Type) generateGeoJSON() (any, error) {
+func validateGeoJson(geoJson *GeoJson) ConfigErrors {
+	if geoJson == nil {
+		return ConfigErrors{{Path: "GeoJson", Message: "'GeoJson' param is required for 'geo_json' type"}}
+	}
+	errs := make(ConfigErrors, 0)
+	if len(geoJson.GeoGeometries) == 0 {
+		errs = append(errs, ConfigError{Path: "GeoJson.GeoGeometries", Message: "at least one geometry is required"})
+	}
+	for i, geometry := range geoJson.GeoGeometries {
+		geometryPath := indexConfigPath("GeoJson.GeoGeometries", i)
+		if !slices.Contains(supportedGeometries, geometry.Type) {
+			errs = append(errs, ConfigError{
+				Path: joinConfigPath(geometryPath, "Type"),
+				Message: fmt.Sprintf("unknown geometry type %q, expected one of: %s",
+					geometry.Type, strings.Join(supportedGeometries, ", ")),
+			})
+		}
+		if geometry.MaxPoints != 0 && geometry.MaxPoints < geometry.MinPoints {
+			errs = append(errs, ConfigError{Path: joinConfigPath(geometryPath, "MaxPoints"), Message: "'MaxPoints' is less than 'MinPoints'"})
+		}
+		if geometry.MinLon > geometry.MaxLon || geometry.MinLat > geometry.MaxLat {
+			errs = append(errs, ConfigError{Path: geometryPath, Message: "min coordinates are greater than max ones"})
+		}
+	}
+	return errs
+}
+
+func (t *Type) generateGeoJSON(gc *GenContext) (any, error) {
 	if t.GeoJson == nil {
 		return nil, errors.New("Geo spec is nil")
 	}
 
 	geometries := make([]map[string]any, 0, len(t.GeoJson.GeoGeometries))
 	for _, geomSpec := range t.GeoJson.GeoGeometries {
-		coords := geomSpec.GenerateCoordinates()
+		coords := geomSpec.GenerateCoordinates(gc)
 		if coords == nil {
 			continue
 		}
@@ -214,12 +244,12 @@ func (t *Type) generateGeoJSON() (any, error) {
 	return string(b), nil
 }
 
-func longitudeInRange(minLon float64, maxLon float64) float64 {
-	lon, _ := gofakeit.LongitudeInRange(minLon, maxLon)
+func longitudeInRange(gc *GenContext, minLon float64, maxLon float64) float64 {
+	lon, _ := gc.faker.LongitudeInRange(minLon, maxLon)
 	return lon
 }
 
-func latitudeInRange(minLat float64, maxLat float64) float64 {
-	lat, _ := gofakeit.LatitudeInRange(minLat, maxLat)
+func latitudeInRange(gc *GenContext, minLat float64, maxLat float64) float64 {
+	lat, _ := gc.faker.LatitudeInRange(minLat, maxLat)
 	return lat
 }
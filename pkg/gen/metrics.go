@@ -0,0 +1,116 @@
+package gen
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/txix-open/isp-kit/metrics"
+)
+
+const (
+	channelSampleInterval = time.Second
+)
+
+// Metrics are Prometheus metrics of generations, they are registered once and shared by generations
+type Metrics struct {
+	records         *prometheus.CounterVec
+	bytes           *prometheus.CounterVec
+	errors          *prometheus.CounterVec
+	latency         *prometheus.HistogramVec
+	channelLength   *prometheus.GaugeVec
+	channelCapacity *prometheus.GaugeVec
+}
+
+func NewMetrics(registry *metrics.Registry) *Metrics {
+	return &Metrics{
+		records: metrics.GetOrRegister(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gogen",
+			Name:      "records_total",
+			Help:      "Number of records written by entity",
+		}, []string{"entity"})),
+		bytes: metrics.GetOrRegister(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gogen",
+			Name:      "written_bytes_total",
+			Help:      "Size of records written by entity before compression",
+		}, []string{"entity"})),
+		errors: metrics.GetOrRegister(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gogen",
+			Name:      "errors_total",
+			Help:      "Number of generation errors by kind",
+		}, []string{"kind"})),
+		latency: metrics.GetOrRegister(registry, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gogen",
+			Name:      "record_generation_seconds",
+			Help:      "Time of generation and encoding of a record",
+			Buckets:   prometheus.ExponentialBuckets(1e-6, 4, 10),
+		}, []string{"entity"})),
+		channelLength: metrics.GetOrRegister(registry, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gogen",
+			Name:      "channel_length",
+			Help:      "Number of items in pipeline channels, full writer channels mean writer backpressure",
+		}, []string{"channel", "entity"})),
+		channelCapacity: metrics.GetOrRegister(registry, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gogen",
+			Name:      "channel_capacity",
+			Help:      "Capacity of pipeline channels",
+		}, []string{"channel", "entity"})),
+	}
+}
+
+// entityMetrics are metrics of the entity with resolved labels, nil metrics are disabled
+type entityMetrics struct {
+	records prometheus.Counter
+	bytes   prometheus.Counter
+	latency prometheus.Observer
+}
+
+func (m *Metrics) entity(filepath string) *entityMetrics {
+	if m == nil {
+		return nil
+	}
+	return &entityMetrics{
+		records: m.records.WithLabelValues(filepath),
+		bytes:   m.bytes.WithLabelValues(filepath),
+		latency: m.latency.WithLabelValues(filepath),
+	}
+}
+
+func (m *entityMetrics) written(bytes int) {
+	if m == nil {
+		return
+	}
+	m.records.Inc()
+	m.bytes.Add(float64(bytes))
+}
+
+func (m *entityMetrics) generated(startedAt time.Time) {
+	if m == nil {
+		return
+	}
+	m.latency.Observe(time.Since(startedAt).Seconds())
+}
+
+// sampleChannels updates lengths of pipeline channels until stop is closed
+func (m *Metrics) sampleChannels(stop <-chan struct{}, tasks chan *iterationTask,
+	results chan *iterationResult, writers []chan writeRequest, entities []Entity) {
+	m.channelCapacity.WithLabelValues("tasks", "").Set(float64(cap(tasks)))
+	m.channelCapacity.WithLabelValues("results", "").Set(float64(cap(results)))
+	for i, ch := range writers {
+		m.channelCapacity.WithLabelValues("writer", entities[i].Config.Filepath).Set(float64(cap(ch)))
+	}
+
+	ticker := time.NewTicker(channelSampleInterval)
+	defer ticker.Stop()
+	for {
+		m.channelLength.WithLabelValues("tasks", "").Set(float64(len(tasks)))
+		m.channelLength.WithLabelValues("results", "").Set(float64(len(results)))
+		for i, ch := range writers {
+			m.channelLength.WithLabelValues("writer", entities[i].Config.Filepath).Set(float64(len(ch)))
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package gen
+
+import (
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// Progress is a snapshot of running generation, counters include previous runs before resume
+type Progress struct {
+	Elapsed time.Duration
+	// Iterations are generated iterations of the shard, TotalIterations is 0 without TotalCount
+	Iterations      int64
+	TotalIterations int64
+	// IterationsPerSecond and rates of entities are measured since the previous snapshot
+	IterationsPerSecond float64
+	// ETA is estimated by the nearest stop condition, it is 0, if it is unknown
+	ETA      time.Duration
+	Entities []EntityProgress
+}
+
+type EntityProgress struct {
+	Filepath string
+	Records  int64
+	Bytes    int64
+	// Count is a limit of entity records, it is 0, if it is not limited
+	Count            int64
+	RecordsPerSecond float64
+	BytesPerSecond   float64
+}
+
+// progressCounters are updated by workers and writers, they are read by the progress reporter
+type progressCounters struct {
+	iterations atomic.Int64
+	entities   []entityCounters
+}
+
+type entityCounters struct {
+	records atomic.Int64
+	bytes   atomic.Int64
+}
+
+func newProgressCounters(state GenerationState, first int64) *progressCounters {
+	c := &progressCounters{
+		entities: make([]entityCounters, len(state.Entities)),
+	}
+	c.iterations.Store(state.Iteration - first)
+	for i, entity := range state.Entities {
+		c.entities[i].records.Store(entity.Written)
+		c.entities[i].bytes.Store(entity.Bytes)
+	}
+	return c
+}
+
+func (c *entityCounters) add(bytes int) {
+	if c == nil {
+		return
+	}
+	c.records.Add(1)
+	c.bytes.Add(int64(bytes))
+}
+
+// progressReporter calls GenerateOptions.Progress periodically until stop is closed
+type progressReporter struct {
+	cfg      *Config
+	counters *progressCounters
+	total    int64
+	targets  []int64
+	callback func(Progress)
+	interval time.Duration
+
+	startedAt time.Time
+	elapsed   time.Duration
+	previous  Progress
+}
+
+func newProgressReporter(cfg *Config, counters *progressCounters, total int64, targets []int64,
+	opts GenerateOptions, elapsed time.Duration) *progressReporter {
+	return &progressReporter{
+		cfg:       cfg,
+		counters:  counters,
+		total:     total,
+		targets:   targets,
+		callback:  opts.Progress,
+		interval:  opts.ProgressInterval,
+		startedAt: time.Now(),
+		elapsed:   elapsed,
+		previous:  Progress{Entities: make([]EntityProgress, len(counters.entities))},
+	}
+}
+
+func (r *progressReporter) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.callback(r.snapshot())
+		}
+	}
+}
+
+func (r *progressReporter) snapshot() Progress {
+	p := Progress{
+		Elapsed:         r.elapsed + time.Since(r.startedAt),
+		Iterations:      r.counters.iterations.Load(),
+		TotalIterations: r.total,
+		Entities:        make([]EntityProgress, len(r.counters.entities)),
+	}
+	seconds := (p.Elapsed - r.previous.Elapsed).Seconds()
+	p.IterationsPerSecond = float64(p.Iterations-r.previous.Iterations) / seconds
+
+	var eta []time.Duration
+	if duration := r.cfg.duration(); duration > 0 {
+		eta = append(eta, duration-p.Elapsed)
+	}
+	if p.TotalIterations > 0 && p.IterationsPerSecond > 0 {
+		eta = append(eta, secondsDuration(float64(p.TotalIterations-p.Iterations)/p.IterationsPerSecond))
+	}
+	for i := range p.Entities {
+		entity := &p.Entities[i]
+		entity.Filepath = r.cfg.Entities[i].Config.Filepath
+		entity.Count = r.cfg.Entities[i].Config.Count
+		entity.Records = r.counters.entities[i].records.Load()
+		entity.Bytes = r.counters.entities[i].bytes.Load()
+		entity.RecordsPerSecond = float64(entity.Records-r.previous.Entities[i].Records) / seconds
+		entity.BytesPerSecond = float64(entity.Bytes-r.previous.Entities[i].Bytes) / seconds
+		if target := r.targets[i]; target > 0 && entity.BytesPerSecond > 0 {
+			eta = append(eta, secondsDuration(float64(max(target-entity.Bytes, 0))/entity.BytesPerSecond))
+		}
+	}
+	if len(eta) > 0 {
+		p.ETA = max(slices.Min(eta), 0).Round(time.Second)
+	}
+	r.previous = p
+	return p
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	if seconds > math.MaxInt64/float64(time.Second) {
+		return math.MaxInt64
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,120 @@
+package gen
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	RampProfile = "ramp"
+	StepProfile = "step"
+
+	maxRateLimiterSleep = 100 * time.Millisecond
+)
+
+// RateProfile changes rate from StartRate to RatePerSecond during Duration,
+// "ramp" grows rate linearly, "step" grows rate by Steps equal steps
+type RateProfile struct {
+	Type      string  `validate:"oneof=ramp step"`
+	StartRate float64 `json:",omitempty" validate:"gte=0"`
+	Duration  string  `validate:"required"`
+	Steps     int     `json:",omitempty" validate:"gte=0"`
+}
+
+func RateProfileStructLevelValidation(sl validator.StructLevel) {
+	profile, _ := sl.Current().Interface().(RateProfile)
+
+	duration, err := time.ParseDuration(profile.Duration)
+	if err != nil || duration <= 0 {
+		sl.ReportError(profile.Duration, "Duration", "", "duration", "expected positive duration like '5m'")
+	}
+	if profile.Type == StepProfile && profile.Steps == 0 {
+		sl.ReportError(profile.Steps, "Steps", "", "required", "'Steps' is required for 'step' profile")
+	}
+}
+
+// shardRate splits rate between shards, so all shards together have the rate
+func shardRate(rate float64, shardCount int) float64 {
+	return rate / float64(max(shardCount, 1))
+}
+
+// rateLimiter is a token bucket, which rate follows the profile, it is used by a single goroutine
+type rateLimiter struct {
+	rate    float64
+	burst   float64
+	profile *RateProfile
+	done    <-chan struct{}
+
+	duration time.Duration
+	start    time.Time
+	last     time.Time
+	tokens   float64
+}
+
+// newRateLimiter returns nil, if rate is not limited, waiting is stopped when done is closed
+func newRateLimiter(rate float64, burst int, profile *RateProfile, done <-chan struct{}) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	now := time.Now()
+	l := &rateLimiter{
+		rate:    rate,
+		burst:   float64(max(burst, 1)),
+		profile: profile,
+		done:    done,
+		start:   now,
+		last:    now,
+		tokens:  1,
+	}
+	if profile != nil {
+		l.duration, _ = time.ParseDuration(profile.Duration)
+	}
+	return l
+}
+
+// wait blocks until the next record is allowed.
+// The record takes a token in advance and waits until the debt is repaid, tokens earned by oversleeping
+// of timers are not limited by the burst, so they are spent by the next records
+func (l *rateLimiter) wait() {
+	if l == nil {
+		return
+	}
+	l.refill(time.Now(), l.burst)
+	l.tokens--
+	for l.tokens < 0 {
+		// rate can change according to the profile, so sleeping is limited to recalculate it
+		sleep := maxRateLimiterSleep
+		if rate := l.currentRate(l.last); rate > 0 {
+			sleep = min(sleep, time.Duration(-l.tokens/rate*float64(time.Second)))
+		}
+		select {
+		case <-l.done:
+			return
+		case <-time.After(sleep):
+		}
+		l.refill(time.Now(), math.Inf(1))
+	}
+}
+
+// refill adds tokens earned since the last refill up to the limit, tokens above the limit are kept
+func (l *rateLimiter) refill(now time.Time, limit float64) {
+	earned := l.currentRate(now) * now.Sub(l.last).Seconds()
+	l.tokens = math.Max(l.tokens, math.Min(limit, l.tokens+earned))
+	l.last = now
+}
+
+func (l *rateLimiter) currentRate(now time.Time) float64 {
+	elapsed := now.Sub(l.start)
+	if l.profile == nil || elapsed >= l.duration {
+		return l.rate
+	}
+
+	progress := float64(elapsed) / float64(l.duration)
+	if l.profile.Type == StepProfile {
+		steps := float64(l.profile.Steps)
+		progress = math.Min(1, (math.Floor(progress*steps)+1)/steps)
+	}
+	return l.profile.StartRate + (l.rate-l.profile.StartRate)*progress
+}
@@ -0,0 +1,238 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	json2 "encoding/json"
+	"io"
+	"iter"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Records generates records of the entity in the calling goroutine, only pulled records are generated.
+// Records are the same as records of the entity written by Write with the same seed,
+// they are generated until TotalCount, Duration or Count of the entity is reached, ctx is canceled or the loop is stopped.
+// Rates and TargetBytes are not applied, errors of records are yielded and generation continues:
+//
+//	for record, err := range generator.Records(ctx, "users") {
+//		...
+//	}
+func (g *Generator) Records(ctx context.Context, entity string) iter.Seq2[map[string]any, error] {
+	return func(yield func(map[string]any, error) bool) {
+		for value, err := range g.values(ctx, entity) {
+			var record map[string]any
+			if err == nil {
+				var ok bool
+				record, ok = value.(map[string]any)
+				if !ok {
+					err = errors.Errorf("record of entity '%s' is %T, not an object", entity, value)
+				}
+			}
+			if !yield(record, err) {
+				return
+			}
+		}
+	}
+}
+
+// RecordsOf is Records decoded into T like a struct with json tags
+func RecordsOf[T any](ctx context.Context, g *Generator, entity string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for value, err := range g.values(ctx, entity) {
+			var record T
+			if err == nil {
+				err = decodeRecord(value, &record)
+			}
+			if !yield(record, err) {
+				return
+			}
+		}
+	}
+}
+
+// JsonRecords is Records encoded into json like lines of ndjson written by Write without the trailing newline.
+// Records are limited to ratePerSecond, if it is positive, RateBurst and RateProfile of the config are not applied
+func (g *Generator) JsonRecords(ctx context.Context, entity string, ratePerSecond float64) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		limiter := newRateLimiter(ratePerSecond, 1, nil, ctx.Done())
+		for value, err := range g.values(ctx, entity) {
+			limiter.wait()
+			if ctx.Err() != nil {
+				return
+			}
+			var record []byte
+			if err == nil {
+				record, err = encodeRecord(value)
+				if err != nil {
+					err = errors.WithMessagef(err, "entity '%s'", entity)
+				}
+			}
+			if !yield(record, err) {
+				return
+			}
+		}
+	}
+}
+
+// values generates records of the entity sequentially, records of other entities are only reserved,
+// so Count and Rate of the entity give the same records as in the full generation
+// nolint:cyclop
+func (g *Generator) values(ctx context.Context, name string) iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		cfg, opts := g.cfg, g.opts
+		index, err := cfg.entityIndex(name)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		typeErrs := cfg.buildTypes()
+		if len(typeErrs) > 0 {
+			yield(nil, typeErrs)
+			return
+		}
+		cfg.WalkFields(func(path string, field *Field, _ bool) {
+			field.path = path
+		})
+		warnings := newWarnings(opts.Logger)
+		defer warnings.flush()
+		gc := newGenContext(opts.Seed, opts.Now, cfg.generateAlphabets(), warnings)
+		first, last := cfg.ShardRange(opts.ShardIndex, opts.ShardCount)
+		state := cfg.prepareGeneration(gc, first, opts.Resume)
+
+		var deadline time.Time
+		if duration := cfg.duration(); duration > 0 {
+			deadline = time.Now().Add(duration - state.Elapsed)
+		}
+		entity := &cfg.Entities[index]
+		for iteration := state.Iteration; iteration < last; iteration++ {
+			if ctx.Err() != nil || (!deadline.IsZero() && time.Now().After(deadline)) {
+				return
+			}
+
+			gc.reset(planStream, iteration, iteration, emptySharedFields)
+			record := int64(-1)
+			for i := range cfg.Entities {
+				reserved := cfg.Entities[i].reserveRecord(gc)
+				if i == index {
+					record = reserved
+				}
+			}
+			if record < 0 {
+				if entity.exhausted() {
+					return
+				}
+				continue
+			}
+
+			gc.reset(sharedFieldsStream, iteration, iteration, emptySharedFields)
+			sharedFields, err := cfg.GenerateSharedFields(gc)
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			gc.reset(entityStream(index), iteration, record, sharedFields)
+			val, err := entity.Field.Generate(gc)
+			if err != nil {
+				err = errors.WithMessagef(err, "entity '%s'", name)
+			}
+			if !yield(val, err) {
+				return
+			}
+		}
+	}
+}
+
+// WriteRecords writes up to count records of the entity pulled like Records into w, count = 0 means all records.
+// Format is json for ndjson or csv with the header, OutputFormat of the entity is used, if it is empty.
+// Writing is stopped by the first error, the number of written records is returned
+func (g *Generator) WriteRecords(ctx context.Context, w io.Writer, entity string, count int, format string) (int, error) {
+	ent, err := g.cfg.Entity(entity)
+	if err != nil {
+		return 0, err
+	}
+	if format == "" {
+		format = ent.Config.OutputFormat
+	}
+	err = ent.CheckFormat(format)
+	if err != nil {
+		return 0, err
+	}
+	if format == CsvFormat {
+		header, err := CsvHeader(ent)
+		if err != nil {
+			return 0, errors.WithMessage(err, "csv header")
+		}
+		_, err = w.Write(header)
+		if err != nil {
+			return 0, errors.WithMessage(err, "write csv header")
+		}
+	}
+
+	written := 0
+	for value, err := range g.values(ctx, entity) {
+		if err != nil {
+			return written, err
+		}
+		var buf *bytes.Buffer
+		switch format {
+		case CsvFormat:
+			buf, err = writeCsv(value, ent)
+		default:
+			buf, err = writeJson(value)
+		}
+		if err != nil {
+			return written, errors.WithMessagef(err, "entity '%s'", entity)
+		}
+		_, err = buf.WriteTo(w)
+		bpool.Put(buf)
+		if err != nil {
+			return written, errors.WithMessage(err, "write record")
+		}
+		written++
+		if written == count {
+			break
+		}
+	}
+	return written, nil
+}
+
+// CheckFormat returns an error, if records of the entity can't be written in the output format like by WriteRecords
+func (ent *Entity) CheckFormat(format string) error {
+	if !slices.Contains(supportedFormats, format) {
+		return errors.Errorf("unknown output format %q", format)
+	}
+	if format == CsvFormat && len(ent.Field.Fields) == 0 && len(ent.Field.OneOfFields) == 0 {
+		return errors.Errorf("entity '%s': csv output requires object field with 'Fields' or 'OneOfFields'", ent.Name())
+	}
+	return nil
+}
+
+// encodeRecord returns a copy of the json line of the value without the newline, buffers of the pool are reused
+func encodeRecord(value any) ([]byte, error) {
+	buf, err := writeJson(value)
+	if err != nil {
+		return nil, err
+	}
+	record := bytes.Clone(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	buf.Reset()
+	bpool.Put(buf)
+	return record, nil
+}
+
+// decodeRecord converts the generated value into dst by json, so dst can use json tags
+func decodeRecord(value any, dst any) error {
+	data, err := json2.Marshal(value)
+	if err != nil {
+		return errors.WithMessage(err, "marshal record")
+	}
+	err = json2.Unmarshal(data, dst)
+	if err != nil {
+		return errors.WithMessage(err, "unmarshal record")
+	}
+	return nil
+}
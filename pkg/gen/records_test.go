@@ -0,0 +1,40 @@
+package gen
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecordsStopAtEntityCount(t *testing.T) {
+	cfg := &Config{
+		Duration: "1h",
+		Entities: []Entity{{
+			Field:  Field{Fields: []Field{{Name: "id", Type: &Type{Type: SequenceType}}}},
+			Config: EntityConfig{Name: "users", Filepath: StdoutTarget},
+		}, {
+			Field:  Field{Fields: []Field{{Name: "id", Type: &Type{Type: SequenceType}}}},
+			Config: EntityConfig{Name: "orders", Filepath: StdoutTarget, Count: 5},
+		}},
+	}
+	err := Validate(cfg)
+	if err != nil {
+		t.Fatalf("validate config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	records := 0
+	for _, err := range New(cfg, WithSeed(testSeed)).Records(ctx, "orders") {
+		if err != nil {
+			t.Fatalf("record %d: %v", records, err)
+		}
+		records++
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("records are not finished after Count of the entity: %v", ctx.Err())
+	}
+	if records != 5 {
+		t.Fatalf("expected 5 records, got %d", records)
+	}
+}
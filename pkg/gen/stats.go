@@ -0,0 +1,179 @@
+package gen
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+const (
+	// sketchPrecision gives 4096 registers and about 1.6% standard error of distinct count
+	sketchPrecision = 12
+	sketchSize      = 1 << sketchPrecision
+)
+
+// FieldStats are statistics of values of the top-level field of the entity
+type FieldStats struct {
+	Name  string `json:",omitempty"`
+	Count int64
+	Nulls int64
+	// Distinct is an estimation of the number of distinct values
+	Distinct int64
+	// Min and Max are set for numbers and strings, strings are compared lexicographically
+	Min any `json:",omitempty"`
+	Max any `json:",omitempty"`
+	// Sketch is a HyperLogLog sketch of values, it is saved in checkpoints to continue estimation after resume
+	Sketch []byte `json:",omitempty"`
+}
+
+// entityStats collects statistics of written records, it is used by the writer goroutine of the entity
+type entityStats struct {
+	// columns are names of fields of object records, non object records have a single unnamed field
+	columns []string
+	fields  []FieldStats
+	buf     []byte
+}
+
+func newEntityStats(entity *Entity, resume []FieldStats) *entityStats {
+	columns := entity.CsvColumns()
+	if len(columns) == 0 {
+		columns = []string{""}
+	}
+	s := &entityStats{
+		columns: columns,
+		fields:  make([]FieldStats, len(columns)),
+	}
+	for i, column := range columns {
+		s.fields[i] = FieldStats{Name: column, Sketch: make([]byte, sketchSize)}
+	}
+	for _, field := range resume {
+		i := s.index(field.Name)
+		if i != -1 && len(field.Sketch) == sketchSize {
+			s.fields[i] = field
+		}
+	}
+	return s
+}
+
+func (s *entityStats) index(name string) int {
+	for i, column := range s.columns {
+		if column == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *entityStats) add(record any) {
+	m, ok := record.(map[string]any)
+	if !ok || s.columns[0] == "" {
+		s.addValue(&s.fields[0], record)
+		return
+	}
+	for i, column := range s.columns {
+		s.addValue(&s.fields[i], m[column])
+	}
+}
+
+func (s *entityStats) addValue(field *FieldStats, value any) {
+	if value == nil {
+		field.Nulls++
+		return
+	}
+	field.Count++
+
+	s.buf = s.buf[:0]
+	switch v := value.(type) {
+	case string:
+		s.buf = append(s.buf, v...)
+		// values of other types than the first one are not compared
+		if m, ok := field.Min.(string); field.Min == nil || ok && v < m {
+			field.Min = v
+		}
+		if m, ok := field.Max.(string); field.Max == nil || ok && v > m {
+			field.Max = v
+		}
+	case bool:
+		s.buf = strconv.AppendBool(s.buf, v)
+	default:
+		if n, ok := toFloat(v); ok {
+			s.buf = strconv.AppendFloat(s.buf, n, 'g', -1, 64)
+			if m, ok := field.Min.(float64); field.Min == nil || ok && n < m {
+				field.Min = n
+			}
+			if m, ok := field.Max.(float64); field.Max == nil || ok && n > m {
+				field.Max = n
+			}
+		} else {
+			s.buf = fmt.Append(s.buf, v)
+		}
+	}
+
+	addToSketch(field.Sketch, mixSeed(fnv64a(s.buf)))
+}
+
+// snapshot returns statistics with current estimations of distinct values
+func (s *entityStats) snapshot() []FieldStats {
+	fields := make([]FieldStats, len(s.fields))
+	for i, field := range s.fields {
+		field.Distinct = estimateDistinct(field.Sketch)
+		field.Sketch = append([]byte(nil), field.Sketch...)
+		fields[i] = field
+	}
+	return fields
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func fnv64a(b []byte) uint64 {
+	h := uint64(14695981039346656037)
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	return h
+}
+
+func addToSketch(sketch []byte, hash uint64) {
+	index := hash >> (64 - sketchPrecision)
+	rank := byte(bits.LeadingZeros64(hash<<sketchPrecision|1<<(sketchPrecision-1)) + 1)
+	if rank > sketch[index] {
+		sketch[index] = rank
+	}
+}
+
+func estimateDistinct(sketch []byte) int64 {
+	if len(sketch) != sketchSize {
+		return 0
+	}
+	sum, zeros := 0.0, 0
+	for _, rank := range sketch {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+	m := float64(sketchSize)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// linear counting is more precise for small cardinalities
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
@@ -0,0 +1,303 @@
+package gen
+
+import (
+	"context"
+	"iter"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	StructTag = "gogen"
+
+	// structEntityFilepath is a placeholder, records of structs are never written
+	structEntityFilepath = StdoutTarget
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// FieldOf builds the field of the struct T by its fields and their gogen tags like `gogen:"type=email,nil=10"`.
+// Nested structs are Fields, slices and arrays are Array, pointers are nilable by 'nil' key.
+// Types of fields are inferred by 'oneof', 'const' keys and Go types, if 'type' key is not set. Keys of the tag:
+//   - type, nil, min, max, format, template - Type, NilChance, Min, Max, DateFormat and Template of the field
+//   - oneof - OneOf values separated by '|', const - Const value
+//   - minlen, maxlen - MinLen and MaxLen of slices, other keys of slices are keys of their items
+//
+// Names of fields are names of json tags, fields with "-" tag of gogen or json are skipped
+func FieldOf[T any]() (*Field, error) {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		return nil, errors.Errorf("expected struct, got %s", t)
+	}
+	fields, err := structFields(t, make(map[reflect.Type]bool))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "struct %s", t)
+	}
+	return &Field{Fields: fields}, nil
+}
+
+// Structs generates count values of T built by FieldOf, they are equal for equal seeds
+func Structs[T any](ctx context.Context, count int, opts ...Option) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		field, err := FieldOf[T]()
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+		name := strings.ToLower(reflect.TypeFor[T]().Name())
+		if name == "" {
+			name = "struct"
+		}
+		cfg := &Config{
+			TotalCount: count,
+			Entities: []Entity{{
+				Field:  *field,
+				Config: EntityConfig{Name: name, Filepath: structEntityFilepath},
+			}},
+		}
+		err = Validate(cfg)
+		if err != nil {
+			var zero T
+			yield(zero, errors.WithMessagef(err, "struct %s", reflect.TypeFor[T]()))
+			return
+		}
+		for value, err := range RecordsOf[T](ctx, New(cfg, opts...), name) {
+			if !yield(value, err) {
+				return
+			}
+		}
+	}
+}
+
+// structFields returns fields of the struct, fields of embedded structs without json names are inlined like in json
+func structFields(t reflect.Type, visiting map[reflect.Type]bool) ([]Field, error) {
+	if visiting[t] {
+		return nil, errors.Errorf("recursive type %s is not supported", t)
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	fields := make([]Field, 0, t.NumField())
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get(StructTag)
+		jsonName, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if tag == "-" || jsonName == "-" {
+			continue
+		}
+
+		embedded := sf.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+		if sf.Anonymous && jsonName == "" && tag == "" && embedded.Kind() == reflect.Struct {
+			inlined, err := structFields(embedded, visiting)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "embedded %s", sf.Name)
+			}
+			fields = append(fields, inlined...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		params, err := parseStructTag(tag)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "field %s", sf.Name)
+		}
+		field, err := structField(sf.Type, params, visiting)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "field %s", sf.Name)
+		}
+		field.Name = sf.Name
+		if jsonName != "" {
+			field.Name = jsonName
+		}
+		fields = append(fields, *field)
+	}
+	return fields, nil
+}
+
+// nolint:cyclop
+func structField(t reflect.Type, params structTagParams, visiting map[reflect.Type]bool) (*Field, error) {
+	nilChance, err := params.int("nil")
+	if err != nil {
+		return nil, err
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if params["type"] == "" {
+		switch {
+		case t == timeType:
+		case t.Kind() == reflect.Struct:
+			fields, err := structFields(t, visiting)
+			if err != nil {
+				return nil, err
+			}
+			return &Field{NilChance: nilChance, Fields: fields}, nil
+		case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+			return nil, errors.Errorf("type of %s is not inferred, set 'type' key", t)
+		case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+			return structArrayField(t, params, nilChance, visiting)
+		}
+	}
+
+	typ, err := structFieldType(t, params)
+	if err != nil {
+		return nil, err
+	}
+	return &Field{NilChance: nilChance, Type: typ}, nil
+}
+
+func structArrayField(t reflect.Type, params structTagParams, nilChance int, visiting map[reflect.Type]bool) (*Field, error) {
+	arr := &Array{}
+	if t.Kind() == reflect.Array {
+		arr.MinLen, arr.MaxLen = t.Len(), t.Len()
+	}
+	minLen, err := params.int("minlen")
+	if err != nil {
+		return nil, err
+	}
+	maxLen, err := params.int("maxlen")
+	if err != nil {
+		return nil, err
+	}
+	if t.Kind() == reflect.Slice {
+		arr.MinLen, arr.MaxLen = minLen, maxLen
+	}
+
+	itemParams := make(structTagParams, len(params))
+	for key, value := range params {
+		if key != "nil" && key != "minlen" && key != "maxlen" {
+			itemParams[key] = value
+		}
+	}
+	value, err := structField(t.Elem(), itemParams, visiting)
+	if err != nil {
+		return nil, errors.WithMessage(err, "item")
+	}
+	arr.Value = value
+	return &Field{NilChance: nilChance, Array: arr}, nil
+}
+
+// nolint:cyclop
+func structFieldType(t reflect.Type, params structTagParams) (*Type, error) {
+	typ := &Type{
+		Type:       params["type"],
+		DateFormat: params["format"],
+		Template:   params["template"],
+	}
+	if typ.Type == "" {
+		switch {
+		case params["oneof"] != "":
+			typ.Type = OneOfType
+		case params["const"] != "":
+			typ.Type = ConstType
+		case t == timeType:
+			typ.Type = DateType
+		case t.Kind() == reflect.String:
+			typ.Type = StringType
+		case t.Kind() == reflect.Bool:
+			typ.Type = BoolType
+		case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+			typ.Type = FloatType
+		case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+			typ.Type = IntType
+		default:
+			return nil, errors.Errorf("type of %s is not inferred, set 'type' key", t)
+		}
+	}
+	// dates are strings in configs, other values are typed by the kind of the field
+	parse := func(value string) (any, error) {
+		return parseStructTagValue(t, value)
+	}
+	if typ.Type == DateType {
+		parse = func(value string) (any, error) {
+			return value, nil
+		}
+	}
+	var err error
+	if value, ok := params["min"]; ok {
+		typ.Min, err = parse(value)
+		if err != nil {
+			return nil, errors.WithMessage(err, "min")
+		}
+	}
+	if value, ok := params["max"]; ok {
+		typ.Max, err = parse(value)
+		if err != nil {
+			return nil, errors.WithMessage(err, "max")
+		}
+	}
+	if value, ok := params["const"]; ok {
+		typ.Const, err = parseStructTagValue(t, value)
+		if err != nil {
+			return nil, errors.WithMessage(err, "const")
+		}
+	}
+	if values, ok := params["oneof"]; ok {
+		for _, value := range strings.Split(values, "|") {
+			v, err := parseStructTagValue(t, value)
+			if err != nil {
+				return nil, errors.WithMessage(err, "oneof")
+			}
+			typ.OneOf = append(typ.OneOf, v)
+		}
+	}
+	return typ, nil
+}
+
+// parseStructTagValue converts the value of the tag to the config value of the field kind, numbers are float64 like in json
+func parseStructTagValue(t reflect.Type, value string) (any, error) {
+	switch {
+	case t.Kind() == reflect.Bool:
+		return strconv.ParseBool(value)
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Float64:
+		return strconv.ParseFloat(value, 64)
+	default:
+		return value, nil
+	}
+}
+
+var structTagKeys = []string{"type", "nil", "min", "max", "format", "template", "oneof", "const", "minlen", "maxlen"}
+
+type structTagParams map[string]string
+
+func parseStructTag(tag string) (structTagParams, error) {
+	params := make(structTagParams)
+	if tag == "" {
+		return params, nil
+	}
+	for _, part := range strings.Split(tag, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, errors.Errorf("invalid tag part '%s', expected key=value", part)
+		}
+		if !slices.Contains(structTagKeys, key) {
+			return nil, errors.Errorf("unknown tag key '%s', expected one of: %s", key, strings.Join(structTagKeys, ", "))
+		}
+		params[key] = value
+	}
+	return params, nil
+}
+
+func (p structTagParams) int(key string) (int, error) {
+	value, ok := p[key]
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.Errorf("expected integer '%s', got '%s'", key, value)
+	}
+	return n, nil
+}
@@ -0,0 +1,54 @@
+package gen
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+type testStruct struct {
+	ID    int64   `json:"id" gogen:"type=sequence,min=1,max=0"`
+	Age   uint8   `json:"age" gogen:"min=18,max=60"`
+	Score float64 `json:"score" gogen:"min=0,max=5"`
+	Ratio float32 `json:"ratio"`
+}
+
+func TestFieldOfInfersNumberTypes(t *testing.T) {
+	field, err := FieldOf[testStruct]()
+	if err != nil {
+		t.Fatalf("field of struct: %v", err)
+	}
+	expected := map[string]string{"id": SequenceType, "age": IntType, "score": FloatType, "ratio": FloatType}
+	for _, f := range field.Fields {
+		if f.Type.Type != expected[f.Name] {
+			t.Errorf("field %s: expected type %s, got %s", f.Name, expected[f.Name], f.Type.Type)
+		}
+	}
+}
+
+func TestStructsGenerateFloats(t *testing.T) {
+	fractional := false
+	records := 0
+	for value, err := range Structs[testStruct](context.Background(), 100, WithSeed(testSeed)) {
+		if err != nil {
+			t.Fatalf("record %d: %v", records, err)
+		}
+		records++
+		if value.Score < 0 || value.Score >= 5 {
+			t.Fatalf("record %d: score %v is out of [0, 5)", records, value.Score)
+		}
+		if value.Ratio < 0 || value.Ratio >= 1 {
+			t.Fatalf("record %d: ratio %v is out of [0, 1)", records, value.Ratio)
+		}
+		if value.Age < 18 || value.Age > 60 {
+			t.Fatalf("record %d: age %d is out of [18, 60]", records, value.Age)
+		}
+		fractional = fractional || value.Score != math.Trunc(value.Score)
+	}
+	if records != 100 {
+		t.Fatalf("expected 100 records, got %d", records)
+	}
+	if !fractional {
+		t.Fatal("expected fractional scores")
+	}
+}
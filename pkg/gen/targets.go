@@ -0,0 +1,27 @@
+package gen
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	StdoutTarget   = "-"
+	FdTargetPrefix = "fd:"
+)
+
+var (
+	// PartPlaceholder is a placeholder of the part number in Filepath of the split output like '{part:05d}'
+	PartPlaceholder = regexp.MustCompile(`\{part(?::0(\d+)d)?\}`)
+)
+
+// IsStreamTarget reports whether records are written into stdout, a file descriptor, a named pipe or a device,
+// which are opened without truncation and can't be resumed or removed
+func IsStreamTarget(path string) bool {
+	if path == StdoutTarget || strings.HasPrefix(path, FdTargetPrefix) {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && !info.Mode().IsRegular() && !info.IsDir()
+}
@@ -0,0 +1,155 @@
+package gen
+
+import (
+	"bytes"
+	json2 "encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// TypeGenerator generates values of the field type,
+// it is shared by generation workers, so it must be safe for concurrent use
+type TypeGenerator interface {
+	Generate(gc *GenContext) (any, error)
+}
+
+// TypeGeneratorFunc is a TypeGenerator of a function
+type TypeGeneratorFunc func(gc *GenContext) (any, error)
+
+func (f TypeGeneratorFunc) Generate(gc *GenContext) (any, error) {
+	return f(gc)
+}
+
+// TypeFactory checks params of the type and creates its generator, it is called for every field of the type,
+// when the config is validated. Errors are reported at the path of the type,
+// paths of ConfigErrors are relative to the type like 'Params.region'
+type TypeFactory func(t *Type) (TypeGenerator, error)
+
+var (
+	typesLock sync.RWMutex
+	types     = make(map[string]TypeFactory)
+)
+
+// RegisterType makes the type available in configs by the name, it is usually called in init of the package of the type.
+// It panics, if the name is empty or already registered or the factory is nil
+func RegisterType(name string, factory TypeFactory) {
+	typesLock.Lock()
+	defer typesLock.Unlock()
+	if name == "" {
+		panic("gen: RegisterType with empty name")
+	}
+	if factory == nil {
+		panic("gen: RegisterType factory is nil for type " + name)
+	}
+	if _, ok := types[name]; ok {
+		panic("gen: RegisterType called twice for type " + name)
+	}
+	types[name] = factory
+}
+
+// RegisteredTypes returns sorted names of built-in and registered types
+func RegisteredTypes() []string {
+	typesLock.RLock()
+	defer typesLock.RUnlock()
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func lookupType(name string) (TypeFactory, bool) {
+	typesLock.RLock()
+	defer typesLock.RUnlock()
+	factory, ok := types[name]
+	return factory, ok
+}
+
+// DecodeParams decodes Params of the type into dst like a struct of params of the custom type, unknown params are errors
+func (t *Type) DecodeParams(dst any) error {
+	data, err := json2.Marshal(t.Params)
+	if err != nil {
+		return errors.WithMessage(err, "marshal params")
+	}
+	dec := json2.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(dst)
+	if err != nil {
+		return ConfigErrors{{Path: "Params", Message: err.Error()}}
+	}
+	return nil
+}
+
+// buildTypes creates generators of types of all fields by registered factories and collects errors of their params
+func (cfg *Config) buildTypes() ConfigErrors {
+	errs := make(ConfigErrors, 0)
+	cfg.WalkFields(func(path string, field *Field, _ bool) {
+		t := field.Type
+		if t == nil {
+			return
+		}
+		t.generator = nil
+		// referenced value is taken from shared fields, the type is ignored then
+		if t.Reference != "" {
+			return
+		}
+		generator, err := t.build()
+		if err != nil {
+			errs = append(errs, cfg.typeErrors(joinConfigPath(path, "Type"), err)...)
+			return
+		}
+		t.generator = generator
+	})
+	return errs
+}
+
+func (t *Type) build() (TypeGenerator, error) {
+	if t.Type == "" {
+		return nil, ConfigErrors{{Path: "Type", Message: "type is not set"}}
+	}
+	factory, ok := lookupType(t.Type)
+	if !ok {
+		return nil, ConfigErrors{{
+			Path:    "Type",
+			Message: fmt.Sprintf("unknown type %q, expected one of: %s", t.Type, strings.Join(RegisteredTypes(), ", ")),
+		}}
+	}
+	generator, err := factory(t)
+	if err != nil {
+		return nil, err
+	}
+	if generator == nil {
+		return nil, errors.Errorf("factory of type '%s' returned nil generator", t.Type)
+	}
+	return generator, nil
+}
+
+// typeErrors places errors of the type factory at the path of the type
+func (cfg *Config) typeErrors(typePath string, err error) ConfigErrors {
+	var (
+		configErrs ConfigErrors
+		configErr  ConfigError
+	)
+	switch {
+	case errors.As(err, &configErrs):
+	case errors.As(err, &configErr):
+		configErrs = ConfigErrors{configErr}
+	default:
+		configErrs = ConfigErrors{{Message: err.Error()}}
+	}
+
+	result := make(ConfigErrors, 0, len(configErrs))
+	for _, e := range configErrs {
+		path := typePath
+		if e.Path != "" {
+			path = joinConfigPath(typePath, e.Path)
+		}
+		result = append(result, ConfigError{Pos: cfg.position(path), Path: path, Message: e.Message})
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package gen
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/txix-open/isp-kit/log"
+)
+
+const (
+	// warningInterval limits repeated warnings of the same field
+	warningInterval = 10 * time.Second
+)
+
+// warnings logs warnings of fields during generation, the same warning of the field is logged
+// once per warningInterval with the number of suppressed repeats, so a broken field doesn't flood the log
+type warnings struct {
+	logger log.Logger
+	lock   sync.Mutex
+	seen   map[warningKey]*warningState
+}
+
+type warningKey struct {
+	path    string
+	message string
+}
+
+type warningState struct {
+	loggedAt   time.Time
+	suppressed int64
+}
+
+// newWarnings returns nil without logger, warnings are disabled then
+func newWarnings(logger log.Logger) *warnings {
+	if logger == nil {
+		return nil
+	}
+	return &warnings{
+		logger: logger,
+		seen:   make(map[warningKey]*warningState),
+	}
+}
+
+func (w *warnings) warn(path string, message string) {
+	if w == nil {
+		return
+	}
+	key := warningKey{path: path, message: message}
+	now := time.Now()
+
+	w.lock.Lock()
+	state, ok := w.seen[key]
+	if ok && now.Sub(state.loggedAt) < warningInterval {
+		state.suppressed++
+		w.lock.Unlock()
+		return
+	}
+	if !ok {
+		state = &warningState{}
+		w.seen[key] = state
+	}
+	suppressed := state.suppressed
+	state.loggedAt, state.suppressed = now, 0
+	w.lock.Unlock()
+
+	fields := []log.Field{log.String("field", path)}
+	if suppressed > 0 {
+		fields = append(fields, log.Int64("suppressed", suppressed))
+	}
+	w.logger.Warn(context.Background(), message, fields...)
+}
+
+// flush logs numbers of warnings suppressed since their last logging
+func (w *warnings) flush() {
+	if w == nil {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for key, state := range w.seen {
+		if state.suppressed > 0 {
+			w.logger.Warn(context.Background(), key.message,
+				log.String("field", key.path), log.Int64("suppressed", state.suppressed))
+			state.suppressed = 0
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+var (
+	bpool = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+	// keys are sorted, so encoded records depend only on generated values
+	json = jsoniter.Config{
+		EscapeHTML:                    false,
+		MarshalFloatWith6Digits:       true,
+		ObjectFieldMustBeSimpleString: true,
+		SortMapKeys:                   true,
+	}.Froze()
+	ErrIsNotObjectForCsv = errors.New("unexpected type for csv")
+)
+
+func writeJson(val interface{}) (*bytes.Buffer, error) {
+	buf, ok := bpool.Get().(*bytes.Buffer)
+	if !ok {
+		return nil, errors.Errorf("failed type assertion to *bytes.Buffer")
+	}
+
+	err := json.NewEncoder(buf).Encode(val)
+	if err != nil {
+		buf.Reset()
+		bpool.Put(buf)
+		return nil, errors.WithMessage(err, "encode json value")
+	}
+
+	return buf, nil
+}
+
+func writeCsv(val interface{}, entity *Entity) (*bytes.Buffer, error) {
+	buf, ok := bpool.Get().(*bytes.Buffer)
+	if !ok {
+		return nil, errors.Errorf("failed type assertion to *bytes.Buffer")
+	}
+
+	switch m := val.(type) {
+	case map[string]interface{}:
+		csvWriter := csv.NewWriter(buf)
+		if entity.Config.CsvSeparator != "" {
+			csvWriter.Comma = []rune(entity.Config.CsvSeparator)[0]
+		}
+
+		columns := make([]string, 0, len(m))
+		for _, column := range entity.CsvColumns() {
+			if v, ok := m[column]; ok {
+				columns = append(columns, fmt.Sprintf("%v", v))
+			} else {
+				columns = append(columns, "")
+			}
+		}
+
+		err := csvWriter.Write(columns)
+		if err != nil {
+			buf.Reset()
+			bpool.Put(buf)
+			return nil, errors.WithMessage(err, "write csv columns")
+		}
+		csvWriter.Flush()
+	default:
+		bpool.Put(buf)
+		return nil, ErrIsNotObjectForCsv
+	}
+
+	return buf, nil
+}
+
+// CsvHeader returns the header line of csv output of the entity
+func CsvHeader(entity *Entity) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	csvWriter := csv.NewWriter(buf)
+	if entity.Config.CsvSeparator != "" {
+		csvWriter.Comma = []rune(entity.Config.CsvSeparator)[0]
+	}
+
+	err := csvWriter.Write(entity.CsvColumns())
+	if err != nil {
+		return nil, errors.WithMessage(err, "write csv columns")
+	}
+	csvWriter.Flush()
+	return buf.Bytes(), nil
+}
+
+// writeRequest is a record to write or a barrier, which flushes the writer and reports result to flushed
+type writeRequest struct {
+	buf *bytes.Buffer
+	// value is a generated record, it is passed for statistics
+	value   any
+	flushed chan<- error
+}
+
+type flusher interface {
+	Flush() error
+}
+
+// newWriterWorker writes records of the entity, stats are optional
+func newWriterWorker(requestsCh <-chan writeRequest, wg *sync.WaitGroup, writer io.Writer, flushEach bool,
+	stats *entityStats, counters *entityCounters, metrics *entityMetrics, errs *errorCollector) {
+	defer wg.Done()
+
+	var writeErr error
+	for req := range requestsCh {
+		if req.flushed != nil {
+			if f, ok := writer.(flusher); ok && writeErr == nil {
+				writeErr = f.Flush()
+			}
+			req.flushed <- writeErr
+			continue
+		}
+
+		if writeErr == nil {
+			size := req.buf.Len()
+			_, err := req.buf.WriteTo(writer)
+			if f, ok := writer.(flusher); ok && err == nil && flushEach {
+				err = f.Flush()
+			}
+			if err != nil {
+				// the rest of records is drained, generation is aborted by collector
+				writeErr = err
+				errs.add(WriteErrorKind, errors.WithMessage(err, "unexpected write error"))
+			} else {
+				counters.add(size)
+				metrics.written(size)
+				if stats != nil {
+					stats.add(req.value)
+				}
+			}
+		}
+		req.buf.Reset()
+		bpool.Put(req.buf)
+	}
+}
+
+// sequencer passes records to writers in order of iterations, so the output doesn't depend on scheduling of workers
+type sequencer struct {
+	writers []chan writeRequest
+	// stats of entities are updated by writers, they are read only after writers flush
+	stats []*entityStats
+	// records receives values of dispatched records, names are names of entities for them
+	records func(record Record)
+	names   []string
+	// window limits iterations, which are planned, but not passed to writers yet
+	window  chan struct{}
+	pending map[int64]*iterationResult
+	state   GenerationState
+	errs    *errorCollector
+	// targets are TargetBytes of entities, results after the iteration, which reaches any of them, are discarded
+	targets []int64
+	stopped chan struct{}
+	// reachedTarget is an index of the entity, which reached its target, or -1
+	reachedTarget int
+	startedAt     time.Time
+
+	checkpoint         func(state GenerationState) error
+	checkpointInterval time.Duration
+	lastCheckpoint     time.Time
+}
+
+func newSequencer(state GenerationState, writers []chan writeRequest, stats []*entityStats, names []string,
+	window int, targets []int64, opts GenerateOptions, errs *errorCollector) *sequencer {
+	s := &sequencer{
+		writers: writers,
+		stats:   stats,
+		records: opts.Records,
+		names:   names,
+		window:  make(chan struct{}, window),
+		pending: make(map[int64]*iterationResult),
+		state: GenerationState{
+			Iteration: state.Iteration,
+			Elapsed:   state.Elapsed,
+			Entities:  slices.Clone(state.Entities),
+		},
+		errs:               errs,
+		targets:            targets,
+		stopped:            make(chan struct{}),
+		reachedTarget:      -1,
+		startedAt:          time.Now(),
+		checkpoint:         opts.Checkpoint,
+		checkpointInterval: opts.CheckpointInterval,
+		lastCheckpoint:     time.Now(),
+	}
+	s.checkTargets()
+	return s
+}
+
+func (s *sequencer) run(resultsCh <-chan *iterationResult) {
+	for result := range resultsCh {
+		s.pending[result.iteration] = result
+		for {
+			next, ok := s.pending[s.state.Iteration]
+			if !ok {
+				break
+			}
+			delete(s.pending, next.iteration)
+			if s.reachedTarget == -1 {
+				s.dispatch(next)
+				s.checkTargets()
+			} else {
+				discard(next)
+			}
+			<-s.window
+
+			if s.checkpoint != nil && time.Since(s.lastCheckpoint) >= s.checkpointInterval {
+				s.saveCheckpoint()
+			}
+		}
+	}
+	if s.checkpoint != nil {
+		s.saveCheckpoint()
+	}
+}
+
+func (s *sequencer) dispatch(result *iterationResult) {
+	var elapsed time.Duration
+	for i, buf := range result.bufs {
+		if result.records[i] < 0 {
+			continue
+		}
+		s.state.Entities[i].Records++
+		if buf == nil {
+			continue
+		}
+		req := writeRequest{buf: buf}
+		if result.values != nil {
+			req.value = result.values[i]
+		}
+		if elapsed == 0 {
+			elapsed = s.elapsed()
+		}
+		s.state.Entities[i].Bytes += int64(buf.Len())
+		s.state.Entities[i].Elapsed = elapsed
+		s.writers[i] <- req
+		s.state.Entities[i].Written++
+		if s.records != nil {
+			s.records(Record{Entity: s.names[i], Iteration: result.iteration, Value: req.value})
+		}
+	}
+	s.state.Iteration++
+}
+
+func (s *sequencer) checkTargets() {
+	for i, target := range s.targets {
+		if target > 0 && s.state.Entities[i].Bytes >= target && s.reachedTarget == -1 {
+			s.reachedTarget = i
+			close(s.stopped)
+			return
+		}
+	}
+}
+
+// elapsed returns generation time including previous runs before resume
+func (s *sequencer) elapsed() time.Duration {
+	return s.state.Elapsed + time.Since(s.startedAt)
+}
+
+func discard(result *iterationResult) {
+	for _, buf := range result.bufs {
+		if buf != nil {
+			buf.Reset()
+			bpool.Put(buf)
+		}
+	}
+}
+
+// saveCheckpoint waits until writers flush all passed records and saves the state
+func (s *sequencer) saveCheckpoint() {
+	s.lastCheckpoint = time.Now()
+	if s.errs.isAborted() {
+		// state of aborted generation is inconsistent, the previous checkpoint is kept
+		return
+	}
+
+	flushed := make(chan error, len(s.writers))
+	for _, ch := range s.writers {
+		ch <- writeRequest{flushed: flushed}
+	}
+	for range s.writers {
+		err := <-flushed
+		if err != nil {
+			s.errs.add(WriteErrorKind, errors.WithMessage(err, "flush before checkpoint"))
+		}
+	}
+	if s.errs.isAborted() {
+		return
+	}
+
+	state := GenerationState{
+		Iteration: s.state.Iteration,
+		Elapsed:   s.elapsed(),
+		Entities:  slices.Clone(s.state.Entities),
+	}
+	for i, stats := range s.stats {
+		if stats != nil {
+			state.Entities[i].Fields = stats.snapshot()
+		}
+	}
+	err := s.checkpoint(state)
+	if err != nil {
+		s.errs.add(WriteErrorKind, errors.WithMessage(err, "save checkpoint"))
+	}
+}
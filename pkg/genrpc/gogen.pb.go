@@ -0,0 +1,352 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: gogen.proto
+
+package genrpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type RecordFormat int32
+
+const (
+	// the format must be set, unspecified format is an invalid argument
+	RecordFormat_RECORD_FORMAT_UNSPECIFIED RecordFormat = 0
+	// records are google.protobuf.Struct
+	RecordFormat_RECORD_FORMAT_STRUCT RecordFormat = 1
+	// records are json objects like lines of ndjson output
+	RecordFormat_RECORD_FORMAT_JSON RecordFormat = 2
+)
+
+// Enum value maps for RecordFormat.
+var (
+	RecordFormat_name = map[int32]string{
+		0: "RECORD_FORMAT_UNSPECIFIED",
+		1: "RECORD_FORMAT_STRUCT",
+		2: "RECORD_FORMAT_JSON",
+	}
+	RecordFormat_value = map[string]int32{
+		"RECORD_FORMAT_UNSPECIFIED": 0,
+		"RECORD_FORMAT_STRUCT":      1,
+		"RECORD_FORMAT_JSON":        2,
+	}
+)
+
+func (x RecordFormat) Enum() *RecordFormat {
+	p := new(RecordFormat)
+	*p = x
+	return p
+}
+
+func (x RecordFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RecordFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_gogen_proto_enumTypes[0].Descriptor()
+}
+
+func (RecordFormat) Type() protoreflect.EnumType {
+	return &file_gogen_proto_enumTypes[0]
+}
+
+func (x RecordFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RecordFormat.Descriptor instead.
+func (RecordFormat) EnumDescriptor() ([]byte, []int) {
+	return file_gogen_proto_rawDescGZIP(), []int{0}
+}
+
+type GenerateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// entity name, it can be omitted for a config with a single entity
+	Entity string `protobuf:"bytes,1,opt,name=entity,proto3" json:"entity,omitempty"`
+	// number of records, 0 means 100 records, it is limited by max count of the server (-max-count of serve),
+	// the stream is finished earlier, when the entity has no more records due to TotalCount, Duration of the config or Count of the entity without Rate
+	Count uint64 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	// seed of the generation, 0 means a random seed
+	Seed uint64 `protobuf:"varint,3,opt,name=seed,proto3" json:"seed,omitempty"`
+	// records per second, 0 means unlimited rate
+	RatePerSecond float64      `protobuf:"fixed64,4,opt,name=rate_per_second,json=ratePerSecond,proto3" json:"rate_per_second,omitempty"`
+	Format        RecordFormat `protobuf:"varint,5,opt,name=format,proto3,enum=gogen.v1.RecordFormat" json:"format,omitempty"`
+	// config of the generation, the config of the server is used, if it is empty
+	Config []byte `protobuf:"bytes,6,opt,name=config,proto3" json:"config,omitempty"`
+	// format of the config: json, yaml or toml, json is the default
+	ConfigFormat  string `protobuf:"bytes,7,opt,name=config_format,json=configFormat,proto3" json:"config_format,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateRequest) Reset() {
+	*x = GenerateRequest{}
+	mi := &file_gogen_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateRequest) ProtoMessage() {}
+
+func (x *GenerateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gogen_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateRequest.ProtoReflect.Descriptor instead.
+func (*GenerateRequest) Descriptor() ([]byte, []int) {
+	return file_gogen_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GenerateRequest) GetEntity() string {
+	if x != nil {
+		return x.Entity
+	}
+	return ""
+}
+
+func (x *GenerateRequest) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *GenerateRequest) GetSeed() uint64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+func (x *GenerateRequest) GetRatePerSecond() float64 {
+	if x != nil {
+		return x.RatePerSecond
+	}
+	return 0
+}
+
+func (x *GenerateRequest) GetFormat() RecordFormat {
+	if x != nil {
+		return x.Format
+	}
+	return RecordFormat_RECORD_FORMAT_UNSPECIFIED
+}
+
+func (x *GenerateRequest) GetConfig() []byte {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *GenerateRequest) GetConfigFormat() string {
+	if x != nil {
+		return x.ConfigFormat
+	}
+	return ""
+}
+
+type Record struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// zero-based index of the record in the stream
+	Index uint64 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	// Types that are valid to be assigned to Value:
+	//
+	//	*Record_Struct
+	//	*Record_Json
+	Value         isRecord_Value `protobuf_oneof:"value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Record) Reset() {
+	*x = Record{}
+	mi := &file_gogen_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Record) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Record) ProtoMessage() {}
+
+func (x *Record) ProtoReflect() protoreflect.Message {
+	mi := &file_gogen_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Record.ProtoReflect.Descriptor instead.
+func (*Record) Descriptor() ([]byte, []int) {
+	return file_gogen_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Record) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Record) GetValue() isRecord_Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *Record) GetStruct() *structpb.Struct {
+	if x != nil {
+		if x, ok := x.Value.(*Record_Struct); ok {
+			return x.Struct
+		}
+	}
+	return nil
+}
+
+func (x *Record) GetJson() []byte {
+	if x != nil {
+		if x, ok := x.Value.(*Record_Json); ok {
+			return x.Json
+		}
+	}
+	return nil
+}
+
+type isRecord_Value interface {
+	isRecord_Value()
+}
+
+type Record_Struct struct {
+	Struct *structpb.Struct `protobuf:"bytes,2,opt,name=struct,proto3,oneof"`
+}
+
+type Record_Json struct {
+	Json []byte `protobuf:"bytes,3,opt,name=json,proto3,oneof"`
+}
+
+func (*Record_Struct) isRecord_Value() {}
+
+func (*Record_Json) isRecord_Value() {}
+
+var File_gogen_proto protoreflect.FileDescriptor
+
+const file_gogen_proto_rawDesc = "" +
+	"\n" +
+	"\vgogen.proto\x12\bgogen.v1\x1a\x1cgoogle/protobuf/struct.proto\"\xe8\x01\n" +
+	"\x0fGenerateRequest\x12\x16\n" +
+	"\x06entity\x18\x01 \x01(\tR\x06entity\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x04R\x05count\x12\x12\n" +
+	"\x04seed\x18\x03 \x01(\x04R\x04seed\x12&\n" +
+	"\x0frate_per_second\x18\x04 \x01(\x01R\rratePerSecond\x12.\n" +
+	"\x06format\x18\x05 \x01(\x0e2\x16.gogen.v1.RecordFormatR\x06format\x12\x16\n" +
+	"\x06config\x18\x06 \x01(\fR\x06config\x12#\n" +
+	"\rconfig_format\x18\a \x01(\tR\fconfigFormat\"p\n" +
+	"\x06Record\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x04R\x05index\x121\n" +
+	"\x06struct\x18\x02 \x01(\v2\x17.google.protobuf.StructH\x00R\x06struct\x12\x14\n" +
+	"\x04json\x18\x03 \x01(\fH\x00R\x04jsonB\a\n" +
+	"\x05value*_\n" +
+	"\fRecordFormat\x12\x1d\n" +
+	"\x19RECORD_FORMAT_UNSPECIFIED\x10\x00\x12\x18\n" +
+	"\x14RECORD_FORMAT_STRUCT\x10\x01\x12\x16\n" +
+	"\x12RECORD_FORMAT_JSON\x10\x022L\n" +
+	"\x0fGenerateService\x129\n" +
+	"\bGenerate\x12\x19.gogen.v1.GenerateRequest\x1a\x10.gogen.v1.Record0\x01B;\n" +
+	"\x10io.txix.gogen.v1P\x01Z%github.com/txix-open/gogen/pkg/genrpcb\x06proto3"
+
+var (
+	file_gogen_proto_rawDescOnce sync.Once
+	file_gogen_proto_rawDescData []byte
+)
+
+func file_gogen_proto_rawDescGZIP() []byte {
+	file_gogen_proto_rawDescOnce.Do(func() {
+		file_gogen_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gogen_proto_rawDesc), len(file_gogen_proto_rawDesc)))
+	})
+	return file_gogen_proto_rawDescData
+}
+
+var file_gogen_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_gogen_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_gogen_proto_goTypes = []any{
+	(RecordFormat)(0),       // 0: gogen.v1.RecordFormat
+	(*GenerateRequest)(nil), // 1: gogen.v1.GenerateRequest
+	(*Record)(nil),          // 2: gogen.v1.Record
+	(*structpb.Struct)(nil), // 3: google.protobuf.Struct
+}
+var file_gogen_proto_depIdxs = []int32{
+	0, // 0: gogen.v1.GenerateRequest.format:type_name -> gogen.v1.RecordFormat
+	3, // 1: gogen.v1.Record.struct:type_name -> google.protobuf.Struct
+	1, // 2: gogen.v1.GenerateService.Generate:input_type -> gogen.v1.GenerateRequest
+	2, // 3: gogen.v1.GenerateService.Generate:output_type -> gogen.v1.Record
+	3, // [3:4] is the sub-list for method output_type
+	2, // [2:3] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_gogen_proto_init() }
+func file_gogen_proto_init() {
+	if File_gogen_proto != nil {
+		return
+	}
+	file_gogen_proto_msgTypes[1].OneofWrappers = []any{
+		(*Record_Struct)(nil),
+		(*Record_Json)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gogen_proto_rawDesc), len(file_gogen_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_gogen_proto_goTypes,
+		DependencyIndexes: file_gogen_proto_depIdxs,
+		EnumInfos:         file_gogen_proto_enumTypes,
+		MessageInfos:      file_gogen_proto_msgTypes,
+	}.Build()
+	File_gogen_proto = out.File
+	file_gogen_proto_goTypes = nil
+	file_gogen_proto_depIdxs = nil
+}
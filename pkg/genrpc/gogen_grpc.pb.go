@@ -0,0 +1,132 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: gogen.proto
+
+package genrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	GenerateService_Generate_FullMethodName = "/gogen.v1.GenerateService/Generate"
+)
+
+// GenerateServiceClient is the client API for GenerateService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// GenerateService streams records generated by the same engine as gogen CLI
+type GenerateServiceClient interface {
+	// Generate streams records of the entity, the stream is finished after count records,
+	// when the entity has no more records or the call is canceled
+	Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Record], error)
+}
+
+type generateServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGenerateServiceClient(cc grpc.ClientConnInterface) GenerateServiceClient {
+	return &generateServiceClient{cc}
+}
+
+func (c *generateServiceClient) Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Record], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GenerateService_ServiceDesc.Streams[0], GenerateService_Generate_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GenerateRequest, Record]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GenerateService_GenerateClient = grpc.ServerStreamingClient[Record]
+
+// GenerateServiceServer is the server API for GenerateService service.
+// All implementations must embed UnimplementedGenerateServiceServer
+// for forward compatibility.
+//
+// GenerateService streams records generated by the same engine as gogen CLI
+type GenerateServiceServer interface {
+	// Generate streams records of the entity, the stream is finished after count records,
+	// when the entity has no more records or the call is canceled
+	Generate(*GenerateRequest, grpc.ServerStreamingServer[Record]) error
+	mustEmbedUnimplementedGenerateServiceServer()
+}
+
+// UnimplementedGenerateServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGenerateServiceServer struct{}
+
+func (UnimplementedGenerateServiceServer) Generate(*GenerateRequest, grpc.ServerStreamingServer[Record]) error {
+	return status.Errorf(codes.Unimplemented, "method Generate not implemented")
+}
+func (UnimplementedGenerateServiceServer) mustEmbedUnimplementedGenerateServiceServer() {}
+func (UnimplementedGenerateServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeGenerateServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GenerateServiceServer will
+// result in compilation errors.
+type UnsafeGenerateServiceServer interface {
+	mustEmbedUnimplementedGenerateServiceServer()
+}
+
+func RegisterGenerateServiceServer(s grpc.ServiceRegistrar, srv GenerateServiceServer) {
+	// If the following call pancis, it indicates UnimplementedGenerateServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&GenerateService_ServiceDesc, srv)
+}
+
+func _GenerateService_Generate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GenerateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GenerateServiceServer).Generate(m, &grpc.GenericServerStream[GenerateRequest, Record]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GenerateService_GenerateServer = grpc.ServerStreamingServer[Record]
+
+// GenerateService_ServiceDesc is the grpc.ServiceDesc for GenerateService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GenerateService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gogen.v1.GenerateService",
+	HandlerType: (*GenerateServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Generate",
+			Handler:       _GenerateService_Generate_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gogen.proto",
+}
@@ -0,0 +1,158 @@
+// Package genrpc exposes generation of records by gRPC, see gogen.proto.
+//
+// Service streams records of the same engine as gogen CLI, it can be served by any grpc.Server:
+//
+//	server := grpc.NewServer()
+//	genrpc.RegisterGenerateServiceServer(server, genrpc.NewService(load, maxCount, logger))
+package genrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative gogen.proto
+
+import (
+	"github.com/pkg/errors"
+	"github.com/txix-open/gogen/pkg/gen"
+	"github.com/txix-open/isp-kit/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DefaultCount is a number of records of requests without count, it is limited by max count of the service
+const DefaultCount = 100
+
+// ConfigLoader returns the config used by requests without an inline config,
+// it is called by every request, because generations of the same config can't run concurrently
+type ConfigLoader func() (*gen.Config, error)
+
+type Service struct {
+	UnimplementedGenerateServiceServer
+
+	load     ConfigLoader
+	maxCount uint64
+	logger   log.Logger
+}
+
+// NewService returns the service, load can be nil, then only requests with an inline config are served.
+// MaxCount limits count of requests like -max-count of serve command
+func NewService(load ConfigLoader, maxCount uint64, logger log.Logger) *Service {
+	return &Service{
+		load:     load,
+		maxCount: maxCount,
+		logger:   logger,
+	}
+}
+
+// nolint:cyclop
+func (s *Service) Generate(req *GenerateRequest, stream grpc.ServerStreamingServer[Record]) error {
+	ctx := stream.Context()
+	if req.GetRatePerSecond() < 0 {
+		return status.Errorf(codes.InvalidArgument, "invalid rate_per_second %v, expected non-negative", req.GetRatePerSecond())
+	}
+	count := req.GetCount()
+	if count == 0 {
+		count = min(DefaultCount, s.maxCount)
+	}
+	if count > s.maxCount {
+		return status.Errorf(codes.InvalidArgument, "invalid count %d, expected from 1 to %d", count, s.maxCount)
+	}
+	format := req.GetFormat()
+	if format != RecordFormat_RECORD_FORMAT_STRUCT && format != RecordFormat_RECORD_FORMAT_JSON {
+		return status.Errorf(codes.InvalidArgument, "invalid format %v, expected RECORD_FORMAT_STRUCT or RECORD_FORMAT_JSON", format)
+	}
+	cfg, err := s.config(req)
+	if err != nil {
+		return err
+	}
+	entity := req.GetEntity()
+	if entity == "" {
+		if len(cfg.Entities) != 1 {
+			return status.Error(codes.InvalidArgument, "'entity' is required for config with several entities")
+		}
+		entity = cfg.Entities[0].Name()
+	}
+	_, err = cfg.Entity(entity)
+	if err != nil {
+		return statusError(err)
+	}
+
+	opts := []gen.Option{gen.WithLogger(s.logger)}
+	if req.GetSeed() != 0 {
+		opts = append(opts, gen.WithSeed(req.GetSeed()))
+	}
+	index := uint64(0)
+	for data, err := range gen.New(cfg, opts...).JsonRecords(ctx, entity, req.GetRatePerSecond()) {
+		if err != nil {
+			return statusError(err)
+		}
+		record, err := newRecord(index, data, format)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "entity '%s': %v", entity, err)
+		}
+		err = stream.Send(record)
+		if err != nil {
+			return err
+		}
+		index++
+		if index == count {
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	s.logger.Debug(ctx, "records streamed", log.String("entity", entity), log.Any("records", index))
+	return nil
+}
+
+func (s *Service) config(req *GenerateRequest) (*gen.Config, error) {
+	if len(req.GetConfig()) > 0 {
+		format := req.GetConfigFormat()
+		if format == "" {
+			format = gen.JsonConfigFormat
+		}
+		cfg, err := gen.Parse(req.GetConfig(), format)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return cfg, nil
+	}
+	if s.load == nil {
+		return nil, status.Error(codes.InvalidArgument, "'config' is required, the server is started without config")
+	}
+	cfg, err := s.load()
+	if err != nil {
+		return nil, status.Error(codes.Internal, errors.WithMessage(err, "load config").Error())
+	}
+	return cfg, nil
+}
+
+func newRecord(index uint64, data []byte, format RecordFormat) (*Record, error) {
+	switch format {
+	case RecordFormat_RECORD_FORMAT_JSON:
+		return &Record{Index: index, Value: &Record_Json{Json: data}}, nil
+	case RecordFormat_RECORD_FORMAT_STRUCT:
+		value := &structpb.Struct{}
+		err := protojson.Unmarshal(data, value)
+		if err != nil {
+			return nil, errors.WithMessage(err, "record is not an object, use RECORD_FORMAT_JSON")
+		}
+		return &Record{Index: index, Value: &Record_Struct{Struct: value}}, nil
+	default:
+		return nil, errors.Errorf("unknown format %v", format)
+	}
+}
+
+// statusError converts errors of the generation into statuses, errors of the config are invalid arguments
+func statusError(err error) error {
+	var configErrs gen.ConfigErrors
+	switch {
+	case errors.Is(err, gen.ErrEntityNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.As(err, &configErrs):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
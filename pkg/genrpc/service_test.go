@@ -0,0 +1,255 @@
+package genrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/txix-open/gogen/pkg/gen"
+	"github.com/txix-open/isp-kit/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	testMaxCount = 50
+
+	testConfig = `{
+		"TotalCount": 1000,
+		"Entities": [{
+			"Field": {"Fields": [
+				{"Name": "id", "Type": {"Type": "sequence", "Min": 1, "Max": 0}},
+				{"Name": "email", "Type": {"Type": "email"}}
+			]},
+			"Config": {"Name": "users", "Filepath": "users.json"}
+		}, {
+			"Field": {"Fields": [{"Name": "n", "Type": {"Type": "int", "Min": 1, "Max": 9}}]},
+			"Config": {"Name": "orders", "Filepath": "orders.json", "Count": 7}
+		}]
+	}`
+)
+
+func newTestClient(t *testing.T) GenerateServiceClient {
+	t.Helper()
+
+	logger, err := log.New()
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+	load := func() (*gen.Config, error) {
+		return gen.Parse([]byte(testConfig), gen.JsonConfigFormat)
+	}
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	RegisterGenerateServiceServer(server, NewService(load, testMaxCount, logger))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return NewGenerateServiceClient(conn)
+}
+
+func generate(ctx context.Context, t *testing.T, client GenerateServiceClient, req *GenerateRequest) ([]*Record, error) {
+	t.Helper()
+
+	stream, err := client.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var records []*Record
+	for {
+		record, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
+
+func TestGenerateFormats(t *testing.T) {
+	client := newTestClient(t)
+
+	jsonRecords, err := generate(context.Background(), t, client, &GenerateRequest{
+		Entity: "users",
+		Count:  10,
+		Seed:   42,
+		Format: RecordFormat_RECORD_FORMAT_JSON,
+	})
+	if err != nil {
+		t.Fatalf("generate json: %v", err)
+	}
+	structRecords, err := generate(context.Background(), t, client, &GenerateRequest{
+		Entity: "users",
+		Count:  10,
+		Seed:   42,
+		Format: RecordFormat_RECORD_FORMAT_STRUCT,
+	})
+	if err != nil {
+		t.Fatalf("generate struct: %v", err)
+	}
+	if len(jsonRecords) != 10 || len(structRecords) != 10 {
+		t.Fatalf("expected 10 records, got %d json and %d struct records", len(jsonRecords), len(structRecords))
+	}
+
+	for i := range jsonRecords {
+		if jsonRecords[i].GetIndex() != uint64(i) || structRecords[i].GetIndex() != uint64(i) {
+			t.Fatalf("record %d: unexpected indexes %d and %d", i, jsonRecords[i].GetIndex(), structRecords[i].GetIndex())
+		}
+		var value map[string]any
+		err := json.Unmarshal(jsonRecords[i].GetJson(), &value)
+		if err != nil {
+			t.Fatalf("record %d: unmarshal json: %v", i, err)
+		}
+		if !reflect.DeepEqual(value, structRecords[i].GetStruct().AsMap()) {
+			t.Fatalf("record %d: json %v differs from struct %v", i, value, structRecords[i].GetStruct().AsMap())
+		}
+		if value["id"] != float64(i+1) {
+			t.Fatalf("record %d: expected id %d, got %v", i, i+1, value["id"])
+		}
+	}
+}
+
+func TestGenerateCount(t *testing.T) {
+	client := newTestClient(t)
+
+	durationConfig := []byte(`{
+		"Duration": "1h",
+		"Entities": [{
+			"Field": {"Fields": [{"Name": "id", "Type": {"Type": "sequence"}}]},
+			"Config": {"Name": "users", "Filepath": "users.json"}
+		}, {
+			"Field": {"Fields": [{"Name": "n", "Type": {"Type": "int"}}]},
+			"Config": {"Name": "orders", "Filepath": "orders.json", "Count": 7}
+		}]
+	}`)
+	tests := []struct {
+		name     string
+		entity   string
+		count    uint64
+		config   []byte
+		expected int
+	}{
+		{name: "default count is limited by max count", entity: "users", count: 0, expected: testMaxCount},
+		{name: "count", entity: "users", count: 3, expected: 3},
+		{name: "entity count", entity: "orders", count: testMaxCount, expected: 7},
+		{name: "entity count with duration", entity: "orders", count: testMaxCount, config: durationConfig, expected: 7},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			records, err := generate(ctx, t, client, &GenerateRequest{
+				Entity: test.entity,
+				Count:  test.count,
+				Config: test.config,
+				Format: RecordFormat_RECORD_FORMAT_JSON,
+			})
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			if len(records) != test.expected {
+				t.Fatalf("expected %d records, got %d", test.expected, len(records))
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	client := newTestClient(t)
+
+	tests := []struct {
+		name string
+		req  *GenerateRequest
+		code codes.Code
+	}{{
+		name: "unspecified format",
+		req:  &GenerateRequest{Entity: "users"},
+		code: codes.InvalidArgument,
+	}, {
+		name: "count above max count",
+		req:  &GenerateRequest{Entity: "users", Count: testMaxCount + 1, Format: RecordFormat_RECORD_FORMAT_JSON},
+		code: codes.InvalidArgument,
+	}, {
+		name: "unknown entity",
+		req:  &GenerateRequest{Entity: "unknown", Format: RecordFormat_RECORD_FORMAT_JSON},
+		code: codes.NotFound,
+	}, {
+		name: "entity of several entities",
+		req:  &GenerateRequest{Format: RecordFormat_RECORD_FORMAT_JSON},
+		code: codes.InvalidArgument,
+	}, {
+		name: "external type",
+		req: &GenerateRequest{
+			Format: RecordFormat_RECORD_FORMAT_JSON,
+			Config: []byte(`{"TotalCount": 1, "Entities": [{"Field": {"Type": {"Type": "external",
+				"ExternalCsvSource": {"Filepath": "/etc/passwd", "TargetField": "root"}}}, "Config": {"Filepath": "-"}}]}`),
+		},
+		code: codes.InvalidArgument,
+	}, {
+		name: "not object record",
+		req: &GenerateRequest{
+			Format: RecordFormat_RECORD_FORMAT_STRUCT,
+			Config: []byte(`{"TotalCount": 1, "Entities": [{"Field": {"Type": {"Type": "int"}}, "Config": {"Filepath": "-"}}]}`),
+		},
+		code: codes.InvalidArgument,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := generate(context.Background(), t, client, test.req)
+			if status.Code(err) != test.code {
+				t.Fatalf("expected %s, got %v", test.code, err)
+			}
+		})
+	}
+}
+
+func TestGenerateInlineConfig(t *testing.T) {
+	client := newTestClient(t)
+
+	records, err := generate(context.Background(), t, client, &GenerateRequest{
+		Count:        5,
+		Format:       RecordFormat_RECORD_FORMAT_STRUCT,
+		ConfigFormat: gen.YamlConfigFormat,
+		Config: []byte(`
+TotalCount: 3
+Entities:
+  - Field: {Fields: [{Name: status, Type: {Type: const, Const: active}}]}
+    Config: {Filepath: statuses.json}
+`),
+	})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records limited by TotalCount, got %d", len(records))
+	}
+	for _, record := range records {
+		if record.GetStruct().AsMap()["status"] != "active" {
+			t.Fatalf("unexpected record %v", record.GetStruct().AsMap())
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/txix-open/gogen/pkg/gen"
+	"github.com/txix-open/gogen/pkg/genrpc"
+	"github.com/txix-open/isp-kit/infra"
+	"github.com/txix-open/isp-kit/infra/pprof"
+	"github.com/txix-open/isp-kit/log"
+	"github.com/txix-open/isp-kit/metrics"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultServeCount    = 100
+	defaultServeMaxCount = 1_000_000
+	// maxInlineConfigSize limits configs of POST /generate
+	maxInlineConfigSize = 1024 * 1024
+)
+
+// responseJson reads requests and writes responses of serve and mock with sorted keys like generated records
+var responseJson = jsoniter.Config{
+	EscapeHTML:                    false,
+	MarshalFloatWith6Digits:       true,
+	ObjectFieldMustBeSimpleString: true,
+	SortMapKeys:                   true,
+}.Froze()
+
+type serveOptions struct {
+	configPath   string
+	configFormat string
+	port         int
+	grpcPort     int
+	maxCount     int
+}
+
+// recordsRequest is a request of records of the entity, parameters are taken from the query
+type recordsRequest struct {
+	entity string
+	count  int
+	seed   uint64
+	format string
+}
+
+func serveCommand(args []string) error {
+	opts := serveOptions{}
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(os.Stdout)
+	flags.StringVar(&opts.configPath, "config", "", "config path, entities of the config are served by GET /entities/{name}")
+	flags.StringVar(&opts.configFormat, "format", "", "config format: json, yaml or toml, default is detected by extension")
+	flags.IntVar(&opts.port, "port", 8080, "http port")
+	flags.IntVar(&opts.grpcPort, "grpc-port", 0, "grpc port of GenerateService, 0 disables grpc")
+	flags.IntVar(&opts.maxCount, "max-count", defaultServeMaxCount, "max records per request")
+	flags.StringVar(&logLevel, "log-level", "info", "log level: debug, info, warn or error")
+	flags.StringVar(&logFormat, "log-format", TextLogFormat, "log format: text or json")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if opts.maxCount <= 0 {
+		return errors.Errorf("invalid -max-count %d, expected positive number", opts.maxCount)
+	}
+
+	logger, err = newLogger(logLevel, logFormat)
+	if err != nil {
+		return err
+	}
+	defer logger.Sync() //nolint:errcheck
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if opts.configPath != "" {
+		// the config is loaded by every request, because generations of the same config can't run concurrently
+		_, err = gen.Load(opts.configPath, opts.configFormat)
+		if err != nil {
+			return errors.WithMessage(err, "load config")
+		}
+	}
+
+	s := &recordsServer{opts: opts}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /entities", s.listEntities)
+	mux.HandleFunc("GET /entities/{name}", s.entityRecords)
+	mux.HandleFunc("POST /generate", s.generate)
+
+	infraServer := infra.NewServer()
+	pprof.RegisterHandlers("/internal", infraServer)
+	infraServer.Handle(metricsPath, metrics.DefaultRegistry.MetricsHandler())
+	infraServer.Handle("/", mux)
+
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- errors.WithMessage(infraServer.ListenAndServe(fmt.Sprintf(":%d", opts.port)), "listen and serve")
+	}()
+	logger.Info(ctx, "server listening", log.String("address", fmt.Sprintf("http://127.0.0.1:%d", opts.port)))
+
+	if opts.grpcPort > 0 {
+		grpcServer, err := s.serveGrpc(ctx, serveErr)
+		if err != nil {
+			return err
+		}
+		defer grpcServer.Stop()
+	}
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+		logger.Info(ctx, "server stopped")
+		return nil
+	}
+}
+
+// serveGrpc starts GenerateService on the same config, errors of serving are sent to serveErr
+func (s *recordsServer) serveGrpc(ctx context.Context, serveErr chan<- error) (*grpc.Server, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.opts.grpcPort))
+	if err != nil {
+		return nil, errors.WithMessage(err, "listen grpc")
+	}
+	var load genrpc.ConfigLoader
+	if s.opts.configPath != "" {
+		load = s.loadConfig
+	}
+	grpcServer := grpc.NewServer()
+	genrpc.RegisterGenerateServiceServer(grpcServer, genrpc.NewService(load, uint64(s.opts.maxCount), logger)) // nolint:gosec
+	go func() {
+		serveErr <- errors.WithMessage(grpcServer.Serve(listener), "serve grpc")
+	}()
+	logger.Info(ctx, "grpc server listening", log.String("address", listener.Addr().String()))
+	return grpcServer, nil
+}
+
+type recordsServer struct {
+	opts serveOptions
+}
+
+func (s *recordsServer) loadConfig() (*gen.Config, error) {
+	if s.opts.configPath == "" {
+		return nil, errors.New("server is started without -config, use POST /generate")
+	}
+	return gen.Load(s.opts.configPath, s.opts.configFormat)
+}
+
+// listEntities returns names of entities of the config
+func (s *recordsServer) listEntities(w http.ResponseWriter, r *http.Request) {
+	cfg, err := s.loadConfig()
+	if err != nil {
+		s.writeError(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+	names := make([]string, len(cfg.Entities))
+	for i := range cfg.Entities {
+		names[i] = cfg.Entities[i].Name()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = responseJson.NewEncoder(w).Encode(names)
+}
+
+// entityRecords streams records of the entity of the config
+func (s *recordsServer) entityRecords(w http.ResponseWriter, r *http.Request) {
+	req, err := s.parseRequest(r, r.PathValue("name"))
+	if err != nil {
+		s.writeError(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	cfg, err := s.loadConfig()
+	if err != nil {
+		s.writeError(r.Context(), w, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeRecords(w, r, cfg, req)
+}
+
+// generate streams records of the entity of the config in the body, the entity can be omitted for a single entity
+func (s *recordsServer) generate(w http.ResponseWriter, r *http.Request) {
+	req, err := s.parseRequest(r, r.URL.Query().Get("entity"))
+	if err != nil {
+		s.writeError(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInlineConfigSize))
+	if err != nil {
+		s.writeError(r.Context(), w, http.StatusBadRequest, errors.WithMessage(err, "read config"))
+		return
+	}
+	cfg, err := gen.Parse(data, inlineConfigFormat(r))
+	if err != nil {
+		s.writeError(r.Context(), w, http.StatusBadRequest, err)
+		return
+	}
+	if req.entity == "" {
+		if len(cfg.Entities) != 1 {
+			s.writeError(r.Context(), w, http.StatusBadRequest, errors.New("'entity' is required for config with several entities"))
+			return
+		}
+		req.entity = cfg.Entities[0].Name()
+	}
+	s.writeRecords(w, r, cfg, req)
+}
+
+func (s *recordsServer) parseRequest(r *http.Request, entity string) (recordsRequest, error) {
+	query := r.URL.Query()
+	req := recordsRequest{
+		entity: entity,
+		count:  defaultServeCount,
+		format: query.Get("format"),
+	}
+	var err error
+	if count := query.Get("count"); count != "" {
+		req.count, err = strconv.Atoi(count)
+		if err != nil || req.count <= 0 || req.count > s.opts.maxCount {
+			return req, errors.Errorf("invalid count %q, expected from 1 to %d", count, s.opts.maxCount)
+		}
+	}
+	if seed := query.Get("seed"); seed != "" {
+		req.seed, err = strconv.ParseUint(seed, 10, 64)
+		if err != nil {
+			return req, errors.Errorf("invalid seed %q", seed)
+		}
+	}
+	switch req.format {
+	case "", gen.CsvFormat:
+	case NdjsonFormat, "json":
+		req.format = "json"
+	default:
+		return req, errors.Errorf("unknown format %q, expected ndjson or csv", req.format)
+	}
+	return req, nil
+}
+
+func (s *recordsServer) writeRecords(w http.ResponseWriter, r *http.Request, cfg *gen.Config, req recordsRequest) {
+	ctx := r.Context()
+	entity, err := cfg.Entity(req.entity)
+	switch {
+	case errors.Is(err, gen.ErrEntityNotFound):
+		s.writeError(ctx, w, http.StatusNotFound, err)
+		return
+	case err != nil:
+		s.writeError(ctx, w, http.StatusBadRequest, err)
+		return
+	}
+
+	format := req.format
+	if format == "" {
+		format = entity.Config.OutputFormat
+	}
+	// errors of records are only logged after the status is sent, so the format is checked before
+	err = entity.CheckFormat(format)
+	if err != nil {
+		s.writeError(ctx, w, http.StatusBadRequest, err)
+		return
+	}
+	opts := []gen.Option{gen.WithLogger(logger)}
+	if req.seed != 0 {
+		opts = append(opts, gen.WithSeed(req.seed))
+	}
+	if format == gen.CsvFormat {
+		w.Header().Set("Content-Type", "text/csv")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+
+	startedAt := time.Now()
+	out := bufio.NewWriterSize(w, bufSize)
+	written, err := gen.New(cfg, opts...).WriteRecords(ctx, out, req.entity, req.count, format)
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		// the status is already sent with the first records, so the error is only logged
+		logger.Error(ctx, "write records", log.String("entity", req.entity), log.Int("records", written), log.Any("error", err))
+		return
+	}
+	logger.Debug(ctx, "records written", log.String("entity", req.entity), log.Int("records", written),
+		log.String("elapsed", time.Since(startedAt).String()))
+}
+
+func (s *recordsServer) writeError(ctx context.Context, w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		logger.Error(ctx, "request failed", log.Any("error", err))
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// inlineConfigFormat detects the config format of POST /generate by the query or the content type, json is the default
+func inlineConfigFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("configFormat"); format != "" {
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case strings.HasSuffix(mediaType, "yaml"):
+		return gen.YamlConfigFormat
+	case strings.HasSuffix(mediaType, "toml"):
+		return gen.TomlConfigFormat
+	default:
+		return gen.JsonConfigFormat
+	}
+}
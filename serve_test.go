@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerateRejectsFileSources(t *testing.T) {
+	var err error
+	logger, err = newLogger("error", JsonLogFormat)
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+	s := &recordsServer{opts: serveOptions{maxCount: defaultServeMaxCount}}
+
+	tests := []struct {
+		name   string
+		config string
+		status int
+	}{{
+		name:   "int",
+		config: `{"TotalCount": 3, "Entities": [{"Field": {"Fields": [{"Name": "n", "Type": {"Type": "int"}}]}, "Config": {"Filepath": "-"}}]}`,
+		status: http.StatusOK,
+	}, {
+		name: "external",
+		config: `{"TotalCount": 3, "Entities": [{"Field": {"Fields": [{"Name": "n", "Type": {"Type": "external",
+			"ExternalCsvSource": {"Filepath": "/etc/passwd", "TargetField": "root"}}}]}, "Config": {"Filepath": "-"}}]}`,
+		status: http.StatusBadRequest,
+	}, {
+		name: "shared external",
+		config: `{"TotalCount": 3, "SharedFields": [{"Name": "s", "Type": {"Type": "external",
+			"ExternalCsvSource": {"Filepath": "/etc/passwd", "TargetField": "root"}}}],
+			"Entities": [{"Field": {"Fields": [{"Name": "n", "Type": {"Reference": "s"}}]}, "Config": {"Filepath": "-"}}]}`,
+		status: http.StatusBadRequest,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/generate?count=3", strings.NewReader(test.config))
+			w := httptest.NewRecorder()
+			s.generate(w, r)
+			if w.Code != test.status {
+				t.Fatalf("expected status %d, got %d: %s", test.status, w.Code, w.Body.String())
+			}
+			if test.status == http.StatusBadRequest && !strings.Contains(w.Body.String(), "not allowed") {
+				t.Fatalf("expected error of file source, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGenerateChecksFormatBeforeRecords(t *testing.T) {
+	var err error
+	logger, err = newLogger("error", JsonLogFormat)
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+	s := &recordsServer{opts: serveOptions{maxCount: defaultServeMaxCount}}
+
+	tests := []struct {
+		name    string
+		query   string
+		config  string
+		status  int
+		records int
+	}{{
+		name:   "csv of not object",
+		query:  "format=csv",
+		config: `{"TotalCount": 3, "Entities": [{"Field": {"Type": {"Type": "int"}}, "Config": {"Filepath": "-"}}]}`,
+		status: http.StatusBadRequest,
+	}, {
+		name:    "csv of object",
+		query:   "format=csv",
+		config:  `{"TotalCount": 3, "Entities": [{"Field": {"Fields": [{"Name": "n", "Type": {"Type": "int"}}]}, "Config": {"Filepath": "-"}}]}`,
+		status:  http.StatusOK,
+		records: 4,
+	}, {
+		name:  "entity count with duration",
+		query: "entity=orders",
+		config: `{"Duration": "1h", "Entities": [
+			{"Field": {"Fields": [{"Name": "n", "Type": {"Type": "int"}}]}, "Config": {"Name": "users", "Filepath": "-"}},
+			{"Field": {"Fields": [{"Name": "n", "Type": {"Type": "int"}}]}, "Config": {"Name": "orders", "Filepath": "orders.json", "Count": 2}}]}`,
+		status:  http.StatusOK,
+		records: 2,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/generate?count=10&"+test.query, strings.NewReader(test.config))
+			w := httptest.NewRecorder()
+			s.generate(w, r)
+			if w.Code != test.status {
+				t.Fatalf("expected status %d, got %d: %s", test.status, w.Code, w.Body.String())
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			lines := strings.Count(w.Body.String(), "\n")
+			if lines != test.records {
+				t.Fatalf("expected %d lines, got %d: %s", test.records, lines, w.Body.String())
+			}
+		})
+	}
+}